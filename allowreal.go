@@ -0,0 +1,7 @@
+//go:build vmockhelper_real
+// +build vmockhelper_real
+
+package vmockhelper
+
+// allowRealByTag allows UseRealAndPrintExpected to call real services in CI
+const allowRealByTag = true
@@ -0,0 +1,8 @@
+//go:build !vmockhelper_real
+// +build !vmockhelper_real
+
+package vmockhelper
+
+// allowRealByTag allows UseRealAndPrintExpected to call real services in CI.  Build with the vmockhelper_real tag to
+// set it
+const allowRealByTag = false
@@ -0,0 +1,172 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+// relayBudget limits how quickly and how many times a mock's calls are relayed to the real service
+type relayBudget struct {
+	// interval is the least time between two calls to the real service, or 0 for no limit
+	interval time.Duration
+	// maxCalls and failTest are the budget the mock was created with, which the usage it shares keeps to
+	maxCalls int
+	failTest bool
+	usage    *budgetUsage
+}
+
+// budgetUsage counts the calls relayed to real services by every mock of a test, which share one budget
+type budgetUsage struct {
+	lock     sync.Mutex
+	next     time.Time
+	calls    int
+	overflow int
+	// maxCalls is the smallest budget of the mocks sharing the usage, which every one of them keeps to, or 0 for no
+	// limit
+	maxCalls int
+	// failTest is set once a mock sharing the usage fails the test when the budget is spent
+	failTest bool
+	// limited is set once a mock of the test has a rate limit or budget, which prints the totals when the test ends
+	limited bool
+}
+
+// testBudgets holds the usage of each test that has relayed calls to a real service, by test name.  Mocks that were
+// not created with a *testing.T share the usage under the empty name, which PrintBudgetSummary prints
+var testBudgets = map[string]*budgetUsage{}
+var testBudgetsLock sync.Mutex
+
+// RateLimit relays at most perSecond calls a second to the real service, including retries, making any call over the
+// limit wait its turn.  It keeps a loop in the code under test from hammering a shared environment
+func RateLimit(perSecond float64) RelayOption {
+	return func(c *relayConfig) {
+		if perSecond > 0 {
+			c.budget.interval = time.Duration(float64(time.Second) / perSecond)
+		}
+	}
+}
+
+// CallBudget relays at most maxCalls calls to real services in a test, counting retries and the calls relayed by every
+// other mock in the test, which all keep to the smallest budget any of them was given.  Once the budget is spent, every
+// other call returns mock values like MockCallsAndPrintExpected, and if failTest is set the test fails.  The number of
+// calls relayed and over budget are printed when the test ends, or by PrintBudgetSummary outside of a test
+func CallBudget(maxCalls int, failTest bool) RelayOption {
+	return func(c *relayConfig) {
+		c.budget.maxCalls = maxCalls
+		c.budget.failTest = failTest
+	}
+}
+
+// PrintBudgetSummary prints how many calls were relayed to real services since the summary was last printed, by mocks
+// that were not created with a *testing.T, whose totals are not printed when a test ends.  It prints nothing if none of
+// those mocks has a rate limit or budget
+func PrintBudgetSummary() {
+	testBudgetsLock.Lock()
+	usage, ok := testBudgets[""]
+	delete(testBudgets, "")
+	testBudgetsLock.Unlock()
+	if ok {
+		if summary := usage.summary(""); summary != "" {
+			emitRequested(summary)
+		}
+	}
+}
+
+// shareWithTest makes the budget count calls against the usage of the test reported to by reporter, which prints its
+// totals when the test ends if any of its mocks are limited.  Outside of a test the budget shares the usage of every
+// other mock outside of a test, until PrintBudgetSummary prints it
+func (b *relayBudget) shareWithTest(reporter gomock.TestReporter) {
+	name := testName(reporter)
+	testBudgetsLock.Lock()
+	usage, ok := testBudgets[name]
+	if !ok {
+		usage = &budgetUsage{}
+		testBudgets[name] = usage
+		if cleanuper, ok := reporter.(interface{ Cleanup(func()) }); ok && name != "" {
+			cleanuper.Cleanup(func() {
+				testBudgetsLock.Lock()
+				delete(testBudgets, name)
+				testBudgetsLock.Unlock()
+				if summary := usage.summary(name); summary != "" {
+					emit(summary)
+				}
+			})
+		}
+	}
+	testBudgetsLock.Unlock()
+	b.share(usage)
+}
+
+// share makes the budget count calls against usage, whose limit becomes the smallest of the budgets sharing it
+func (b *relayBudget) share(usage *budgetUsage) {
+	usage.lock.Lock()
+	if b.maxCalls > 0 && (usage.maxCalls == 0 || b.maxCalls < usage.maxCalls) {
+		usage.maxCalls = b.maxCalls
+	}
+	usage.failTest = usage.failTest || b.failTest
+	usage.limited = usage.limited || b.maxCalls > 0 || b.interval > 0
+	usage.lock.Unlock()
+	b.usage = usage
+}
+
+// spend takes a call from the budget, returning false if the budget has been spent
+func (b *relayBudget) spend() bool {
+	b.usage.lock.Lock()
+	defer b.usage.lock.Unlock()
+	if b.usage.maxCalls > 0 && b.usage.calls >= b.usage.maxCalls {
+		b.usage.overflow++
+		return false
+	}
+	b.usage.calls++
+	return true
+}
+
+// limit returns the budget shared by every mock counting against the same usage, and whether spending it fails the
+// test
+func (b *relayBudget) limit() (int, bool) {
+	b.usage.lock.Lock()
+	defer b.usage.lock.Unlock()
+	return b.usage.maxCalls, b.usage.failTest
+}
+
+// wait blocks until the rate limit allows another call to the real service
+func (b *relayBudget) wait() {
+	if b.interval <= 0 {
+		return
+	}
+	b.usage.lock.Lock()
+	now := time.Now()
+	if b.usage.next.Before(now) {
+		b.usage.next = now
+	}
+	start := b.usage.next
+	b.usage.next = b.usage.next.Add(b.interval)
+	b.usage.lock.Unlock()
+	time.Sleep(time.Until(start))
+}
+
+// exhausted reports a call that was not relayed because the budget has been spent, returning the note printed above it
+func (b *relayBudget) exhausted(reporter gomock.TestReporter, mockAlias string, methodName string) string {
+	maxCalls, failTest := b.limit()
+	note := fmt.Sprintf("over the budget of %d real calls, returned mock values", maxCalls)
+	if failTest && reporter != nil {
+		reporter.Errorf("vmockhelper: %s.%s was called after the budget of %d real calls was spent", mockAlias, methodName, maxCalls)
+	}
+	return note
+}
+
+// summary returns how many calls the test named name relayed to real services, or nothing if none of its mocks are
+// limited.  The empty name stands for the mocks outside of a test
+func (u *budgetUsage) summary(name string) string {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if !u.limited {
+		return ""
+	}
+	if name == "" {
+		name = "mocks outside of a test"
+	}
+	return fmt.Sprintf("\nvmockhelper: %s made %d real calls, and %d calls over budget returned mock values\n", name, u.calls, u.overflow)
+}
@@ -0,0 +1,87 @@
+package vmockhelper
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// errorReporter is a gomock.TestReporter that is not a *testing.T, counting the errors reported to it
+type errorReporter struct {
+	errors []string
+}
+
+func (r *errorReporter) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *errorReporter) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestCallBudgetIsSharedByEveryMockInTheTest(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	real := &fakeService{name: "Alice"}
+
+	t.Run("budget", func(t *testing.T) {
+		unlimited := NewMockTestService(gomock.NewController(t))
+		UseRealAndPrintExpected(unlimited, real, "unlimited")
+		limited := NewMockTestService(gomock.NewController(t))
+		UseRealAndPrintExpected(limited, real, "limited", CallBudget(1, false))
+
+		thing, _ := limited.Get(context.Background(), "1")
+		assert.Equal(t, "Alice", thing.Name)
+		thing, _ = unlimited.Get(context.Background(), "2")
+		assert.Nil(t, thing, "a mock without a budget keeps to the budget of the test")
+	})
+
+	assert.Equal(t, []string{"Get"}, real.called())
+	assert.Contains(t, buffer.String(), "over the budget of 1 real calls, returned mock values")
+	assert.Contains(t, buffer.String(), "vmockhelper: TestCallBudgetIsSharedByEveryMockInTheTest/budget made 1 real calls, and 1 calls over budget returned mock values")
+}
+
+func TestCallBudgetOutsideOfATest(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	PrintBudgetSummary()
+	reporter := &errorReporter{}
+	real := &fakeService{name: "Alice"}
+
+	limited := NewMockTestService(gomock.NewController(reporter))
+	UseRealAndPrintExpected(limited, real, "limited", CallBudget(2, true))
+	unlimited := NewMockTestService(gomock.NewController(reporter))
+	UseRealAndPrintExpected(unlimited, real, "unlimited")
+	_, _ = limited.Get(context.Background(), "1")
+	_, _ = unlimited.Get(context.Background(), "2")
+	_, _ = unlimited.Get(context.Background(), "3")
+
+	assert.Len(t, real.called(), 2)
+	assert.Equal(t, []string{"vmockhelper: unlimited.Get was called after the budget of 2 real calls was spent"}, reporter.errors)
+	assert.NotContains(t, buffer.String(), "made 2 real calls")
+
+	PrintBudgetSummary()
+	assert.Contains(t, buffer.String(), "vmockhelper: mocks outside of a test made 2 real calls, and 1 calls over budget returned mock values")
+
+	buffer.Reset()
+	PrintBudgetSummary()
+	assert.Empty(t, buffer.String(), "the summary is printed once")
+}
+
+func TestPrintBudgetSummaryUnderDiscardSink(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	requested := &BufferSink{}
+	SetSink(DiscardSink(requested))
+	defer SetSink(nil)
+	PrintBudgetSummary()
+
+	mock := NewMockTestService(gomock.NewController(&errorReporter{}))
+	UseRealAndPrintExpected(mock, &fakeService{name: "Alice"}, "svc", RateLimit(1000))
+	_, _ = mock.Get(context.Background(), "1")
+	PrintBudgetSummary()
+
+	assert.Contains(t, requested.String(), "vmockhelper: mocks outside of a test made 1 real calls, and 0 calls over budget returned mock values")
+}
@@ -0,0 +1,61 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// packagePath is the import path of this package, which may be vendored
+var packagePath = reflect.TypeOf(Call{}).PkgPath()
+
+// maxCallSiteDepth is how many stack frames are searched for the code that called a mock
+const maxCallSiteDepth = 64
+
+// callSite returns the file, line and function of the code under test that called a mock, or an empty string if it
+// can not be found.  It must be called while answering the call.  Frames in vmockhelper, gomock, reflect and the
+// runtime are skipped, along with the first frame that calls gomock, which is the generated mock itself
+func callSite() string {
+	pcs := make([]uintptr, maxCallSiteDepth)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	calledGomock := false
+	skippedMock := false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case isGomockFrame(frame):
+			calledGomock = true
+		case isInternalFrame(frame):
+		case calledGomock && !skippedMock:
+			skippedMock = true
+		case calledGomock:
+			return fmt.Sprintf("%s:%d (%s)", shortFile(frame.File), frame.Line, shortFunction(frame.Function))
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isGomockFrame(frame runtime.Frame) bool {
+	return strings.Contains(frame.Function, "github.com/golang/mock/gomock.")
+}
+
+// isInternalFrame returns whether a frame is in vmockhelper, reflect or the runtime
+func isInternalFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, packagePath+".") ||
+		strings.HasPrefix(frame.Function, "reflect.") ||
+		strings.HasPrefix(frame.Function, "runtime.")
+}
+
+// shortFile returns the last directory and name of a file
+func shortFile(file string) string {
+	return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file))
+}
+
+// shortFunction returns the name of a function qualified by its package name rather than its import path
+func shortFunction(function string) string {
+	return function[strings.LastIndex(function, "/")+1:]
+}
@@ -0,0 +1,178 @@
+// Package checker contains an analyzer that reports vmockhelper calls left behind in test code.  The recording helpers
+// print to the console and UseRealAndPrintExpected calls real services, so none of them should be committed
+package checker
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const vmockhelperPath = "github.com/short-hop/vmockhelper"
+
+// helpers are the vmockhelper functions that should not be left in test code
+var helpers = map[string]bool{
+	"UseRealAndPrintExpected":   true,
+	"MockCallsAndPrintExpected": true,
+	"Record":                    true,
+	"PrintTestCase":             true,
+	"GetCredentials":            true,
+}
+
+// Analyzer reports calls to vmockhelper recording helpers in _test.go files.  Calls made as a statement of their own
+// come with a suggested fix that deletes the line.  When every use of vmockhelper in a file is deleted, the fix for the
+// last call in the file also deletes the import
+var Analyzer = &analysis.Analyzer{
+	Name:     "vmockhelper",
+	Doc:      "reports vmockhelper recording helpers left in test code",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{(*ast.ExprStmt)(nil), (*ast.CallExpr)(nil)}
+	reported := map[*ast.CallExpr]bool{}
+	// deletions holds the diagnostics with a fix that deletes a call, by file, which are reported once every call in
+	// the file is known
+	deletions := map[*token.File][]analysis.Diagnostic{}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		if !strings.HasSuffix(pass.Fset.File(n.Pos()).Name(), "_test.go") {
+			return
+		}
+		switch node := n.(type) {
+		case *ast.ExprStmt:
+			call, ok := node.X.(*ast.CallExpr)
+			if !ok {
+				return
+			}
+			name, ok := helperName(pass, call)
+			if !ok {
+				return
+			}
+			reported[call] = true
+			file := pass.Fset.File(node.Pos())
+			deletions[file] = append(deletions[file], analysis.Diagnostic{
+				Pos:     call.Pos(),
+				End:     call.End(),
+				Message: "vmockhelper." + name + " should not be committed",
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message:   "Delete the call to vmockhelper." + name,
+					TextEdits: []analysis.TextEdit{deleteLine(pass, node)},
+				}},
+			})
+		case *ast.CallExpr:
+			if reported[node] {
+				return
+			}
+			if name, ok := helperName(pass, node); ok {
+				pass.Reportf(node.Pos(), "vmockhelper.%s should not be committed", name)
+			}
+		}
+	})
+
+	for _, file := range pass.Files {
+		diagnostics := deletions[pass.Fset.File(file.Pos())]
+		if len(diagnostics) == 0 {
+			continue
+		}
+		if edit, ok := deleteImport(pass, file, diagnostics); ok {
+			fix := &diagnostics[len(diagnostics)-1].SuggestedFixes[0]
+			fix.TextEdits = append(fix.TextEdits, edit)
+		}
+		for _, diagnostic := range diagnostics {
+			pass.Report(diagnostic)
+		}
+	}
+	return nil, nil
+}
+
+// helperName returns the name of the vmockhelper helper called by call, if it calls one
+func helperName(pass *analysis.Pass, call *ast.CallExpr) (string, bool) {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return "", false
+	}
+	fn, ok := pass.TypesInfo.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != vmockhelperPath || !helpers[fn.Name()] {
+		return "", false
+	}
+	return fn.Name(), true
+}
+
+// deleteLine returns an edit that deletes the lines a node is on
+func deleteLine(pass *analysis.Pass, node ast.Node) analysis.TextEdit {
+	file := pass.Fset.File(node.Pos())
+	start := file.LineStart(file.Line(node.Pos()))
+	end := node.End()
+	if endLine := file.Line(node.End()); endLine < file.LineCount() {
+		end = file.LineStart(endLine + 1)
+	}
+	return analysis.TextEdit{Pos: start, End: end}
+}
+
+// deleteImport returns an edit that deletes the import of vmockhelper from file, if every use of it is in a call that
+// diagnostics delete.  Blank and dot imports are left alone
+func deleteImport(pass *analysis.Pass, file *ast.File, diagnostics []analysis.Diagnostic) (analysis.TextEdit, bool) {
+	decl, spec := findImport(file)
+	if spec == nil || (spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".")) {
+		return analysis.TextEdit{}, false
+	}
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || used {
+			return !used
+		}
+		if pkg, ok := pass.TypesInfo.Uses[ident].(*types.PkgName); ok && pkg.Imported().Path() == vmockhelperPath {
+			used = !deleted(ident.Pos(), diagnostics)
+		}
+		return true
+	})
+	if used {
+		return analysis.TextEdit{}, false
+	}
+	if len(decl.Specs) == 1 {
+		return deleteLine(pass, decl), true
+	}
+	return deleteLine(pass, spec), true
+}
+
+// findImport returns the import of vmockhelper in file and the declaration it is in, or nils if there is none
+func findImport(file *ast.File) (*ast.GenDecl, *ast.ImportSpec) {
+	for _, d := range file.Decls {
+		decl, ok := d.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		for _, s := range decl.Specs {
+			spec := s.(*ast.ImportSpec)
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == vmockhelperPath {
+				return decl, spec
+			}
+		}
+	}
+	return nil, nil
+}
+
+// deleted returns whether pos is in the lines deleted by the fix of one of diagnostics
+func deleted(pos token.Pos, diagnostics []analysis.Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		edit := diagnostic.SuggestedFixes[0].TextEdits[0]
+		if edit.Pos <= pos && pos < edit.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+// Command vmockhelpercheck reports vmockhelper recording helpers left in test code.  It can be run on its own, or by
+// go vet with go vet -vettool=$(which vmockhelpercheck) ./...
+package main
+
+import (
+	"github.com/short-hop/vmockhelper/checker"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(checker.Analyzer)
+}
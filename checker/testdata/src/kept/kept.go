@@ -0,0 +1,4 @@
+package kept
+
+// mock stands in for a gomock mock
+var mock interface{}
@@ -0,0 +1,15 @@
+package kept
+
+import (
+	"testing"
+
+	"github.com/short-hop/vmockhelper"
+)
+
+func TestKept(t *testing.T) {
+	vmockhelper.HoistLargeValues(80)
+	vmockhelper.MockCallsAndPrintExpected(mock, "mock")        // want `vmockhelper.MockCallsAndPrintExpected should not be committed`
+	if err := vmockhelper.GetCredentials("demo"); err != nil { // want `vmockhelper.GetCredentials should not be committed`
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,8 @@
+package notest
+
+import "github.com/short-hop/vmockhelper"
+
+// Setup is not test code, so its helpers are not reported
+func Setup() {
+	vmockhelper.Record()
+}
@@ -0,0 +1,4 @@
+package removed
+
+// mock and real stand in for a gomock mock and the service it mocks
+var mock, real interface{}
@@ -0,0 +1,14 @@
+package removed
+
+import (
+	"testing"
+
+	"github.com/short-hop/vmockhelper"
+)
+
+func TestRemoved(t *testing.T) {
+	vmockhelper.UseRealAndPrintExpected(mock, real, "mock") // want `vmockhelper.UseRealAndPrintExpected should not be committed`
+	vmockhelper.Record()                                    // want `vmockhelper.Record should not be committed`
+	t.Log("running")
+	vmockhelper.PrintTestCase() // want `vmockhelper.PrintTestCase should not be committed`
+}
@@ -0,0 +1,108 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/mock/gomock"
+)
+
+var dedupeExpected bool
+
+// expectationTally counts the distinct expected calls printed in one test
+type expectationTally struct {
+	// order holds each distinct expected call in the order it was first printed
+	order []string
+	// declarations holds the variables hoisted for each expected call
+	declarations map[string]string
+	counts       map[string]int
+}
+
+// tallies holds the tally of every test that has printed an expected call, by test name
+var tallies = map[string]*expectationTally{}
+var talliesLock sync.Mutex
+
+// DedupeExpected makes the printers print each distinct expected call only once per test, however many times it is
+// made, and nothing each time it is made again.  When the test ends, every distinct expected call it made is printed
+// again along with how many times it was made, ready to paste.  Expected calls are counted even while DiscardSink is
+// the sink, which sends the summary where it sends requested output.  Calling DedupeExpected(false) goes back to
+// printing every call
+func DedupeExpected(enabled bool) {
+	dedupeExpected = enabled
+}
+
+// PrintExpectedSummary prints every distinct expected call counted since the summary was last printed, for tests whose
+// mocks were not created with a *testing.T, whose summary is not printed when they end
+func PrintExpectedSummary() {
+	talliesLock.Lock()
+	var names []string
+	for name := range tallies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var summaries []string
+	for _, name := range names {
+		summaries = append(summaries, tallies[name].summary(name))
+		delete(tallies, name)
+	}
+	talliesLock.Unlock()
+	for _, summary := range summaries {
+		emitRequested(summary)
+	}
+}
+
+// tallyExpected counts an expected call made in the test reported to by reporter, returning how many times the test
+// has made it.  The first expected call counted in a test prints the test's summary when the test ends
+func tallyExpected(reporter gomock.TestReporter, declarations string, expected string) int {
+	name := testName(reporter)
+	talliesLock.Lock()
+	defer talliesLock.Unlock()
+	tally, ok := tallies[name]
+	if !ok {
+		tally = &expectationTally{declarations: map[string]string{}, counts: map[string]int{}}
+		tallies[name] = tally
+		if cleanuper, ok := reporter.(interface{ Cleanup(func()) }); ok {
+			cleanuper.Cleanup(func() {
+				talliesLock.Lock()
+				tally, ok := tallies[name]
+				delete(tallies, name)
+				talliesLock.Unlock()
+				if ok {
+					emitRequested(tally.summary(name))
+				}
+			})
+		}
+	}
+	key := declarations + expected
+	tally.counts[key]++
+	if tally.counts[key] == 1 {
+		tally.order = append(tally.order, key)
+		tally.declarations[key] = declarations
+	}
+	return tally.counts[key]
+}
+
+// summary returns every distinct expected call in the tally, expecting each to be called as many times as it was.
+// Expected calls with hoisted variables are wrapped in a block, since the variables of different expected calls can
+// have the same names
+func (t *expectationTally) summary(name string) string {
+	title := "Distinct expected calls"
+	if name != "" {
+		title += " made by " + name
+	}
+	summary := fmt.Sprintf("\n// %s\n", title)
+	for _, key := range t.order {
+		expected := strings.TrimPrefix(key, t.declarations[key])
+		if count := t.counts[key]; count > 1 {
+			expected += fmt.Sprintf(".Times(%d)", count)
+		}
+		if declarations := t.declarations[key]; declarations != "" {
+			summary += formatCode("{\n"+declarations+expected+"\n}") + "\n"
+		} else {
+			summary += expected + "\n"
+		}
+	}
+	return summary
+}
@@ -0,0 +1,54 @@
+package vmockhelper
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeExpected(t *testing.T) {
+	buffer := useBuffer(t)
+	DedupeExpected(true)
+	defer DedupeExpected(false)
+
+	var printed string
+	t.Run("calls", func(t *testing.T) {
+		mock := NewMockTestService(gomock.NewController(t))
+		MockCallsAndPrintExpected(mock, "svc")
+		for i := 0; i < 1000; i++ {
+			_, _ = mock.Get(context.Background(), "1")
+		}
+		_, _ = mock.Get(context.Background(), "2")
+		printed = buffer.String()
+	})
+
+	assert.Equal(t, 1, strings.Count(printed, `svc.EXPECT().Get(gomock.Any(), "1")`))
+	assert.Equal(t, 1, strings.Count(printed, `svc.EXPECT().Get(gomock.Any(), "2")`))
+	summary := strings.TrimPrefix(buffer.String(), printed)
+	assert.Equal(t, `
+// Distinct expected calls made by TestDedupeExpected/calls
+svc.EXPECT().Get(gomock.Any(), "1").Return(nil, nil).Times(1000)
+svc.EXPECT().Get(gomock.Any(), "2").Return(nil, nil)
+`, summary)
+}
+
+func TestPrintExpectedSummary(t *testing.T) {
+	buffer := useBuffer(t)
+	DedupeExpected(true)
+	defer DedupeExpected(false)
+	mock := NewMockTestService(gomock.NewController(nil))
+	MockCallsAndPrintExpected(mock, "svc")
+
+	_, _ = mock.Get(context.Background(), "1")
+	_, _ = mock.Get(context.Background(), "1")
+	buffer.Reset()
+	PrintExpectedSummary()
+
+	assert.Equal(t, `
+// Distinct expected calls
+svc.EXPECT().Get(gomock.Any(), "1").Return(nil, nil).Times(2)
+`, buffer.String())
+}
@@ -0,0 +1,86 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DiagramFormat is a language sequence diagrams can be printed in
+type DiagramFormat int
+
+const (
+	// Mermaid prints a Mermaid sequenceDiagram, which GitHub and GitLab render in markdown
+	Mermaid DiagramFormat = iota
+	// PlantUML prints a PlantUML sequence diagram
+	PlantUML
+)
+
+// diagramSummaryLength is the longest an argument or return value is printed in a sequence diagram
+const diagramSummaryLength = 40
+
+// diagramCaller is the participant that makes every call to a mock
+const diagramCaller = "Test"
+
+// PrintSequenceDiagram prints a sequence diagram of the code under test calling each mock, with the method names and a
+// short summary of the arguments and return values of every call recorded since Record was called
+func PrintSequenceDiagram(format DiagramFormat) {
+	emitRequested(sequenceDiagram(format, recorded()))
+}
+
+func sequenceDiagram(format DiagramFormat, calls []Call) string {
+	var participants []string
+	seen := map[string]bool{}
+	for _, call := range calls {
+		if !seen[call.alias] {
+			seen[call.alias] = true
+			participants = append(participants, call.alias)
+		}
+	}
+
+	var lines []string
+	switch format {
+	case PlantUML:
+		lines = append(lines, "@startuml", "participant "+diagramCaller)
+		for _, participant := range participants {
+			lines = append(lines, "participant "+participant)
+		}
+		for _, call := range calls {
+			lines = append(lines,
+				fmt.Sprintf("%s -> %s: %s(%s)", diagramCaller, call.alias, call.method, summarizeValues(call.args)),
+				fmt.Sprintf("%s --> %s: %s", call.alias, diagramCaller, summarizeValues(call.returns)),
+			)
+		}
+		lines = append(lines, "@enduml")
+	default:
+		lines = append(lines, "sequenceDiagram", "    participant "+diagramCaller)
+		for _, participant := range participants {
+			lines = append(lines, "    participant "+participant)
+		}
+		for _, call := range calls {
+			// Semicolons end a statement in Mermaid, so they are written as an entity code
+			lines = append(lines,
+				fmt.Sprintf("    %s->>%s: %s(%s)", diagramCaller, call.alias, call.method, strings.Replace(summarizeValues(call.args), ";", "#59;", -1)),
+				fmt.Sprintf("    %s-->>%s: %s", call.alias, diagramCaller, strings.Replace(summarizeValues(call.returns), ";", "#59;", -1)),
+			)
+		}
+	}
+	return "\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// summarizeValues returns a short summary of the code of each value, with contexts written as ctx
+func summarizeValues(values []reflect.Value) string {
+	var summaries []string
+	for _, value := range values {
+		if value.IsValid() && isContext(value) {
+			summaries = append(summaries, "ctx")
+			continue
+		}
+		summary := []rune(renderValue(value))
+		if len(summary) > diagramSummaryLength {
+			summary = append(summary[:diagramSummaryLength-3], []rune("...")...)
+		}
+		summaries = append(summaries, string(summary))
+	}
+	return strings.Join(summaries, ", ")
+}
@@ -0,0 +1,115 @@
+package vmockhelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+// Where the values returned by an intercepted call came from
+const (
+	// sourceReal values were returned by the real service
+	sourceReal = "real"
+	// sourceMocked values were given to MockCallsAndPrintExpected
+	sourceMocked = "mocked"
+	// sourceFilled values were filled in while recording
+	sourceFilled = "filled"
+	// sourceZero values are zero values
+	sourceZero = "zero"
+)
+
+var eventLog io.Writer
+var eventLogLock sync.Mutex
+
+// callEvent is a call intercepted by a mock, as written to the event log
+type callEvent struct {
+	Time       time.Time `json:"time"`
+	Test       string    `json:"test,omitempty"`
+	Goroutine  int64     `json:"goroutine"`
+	Alias      string    `json:"alias"`
+	Method     string    `json:"method"`
+	Args       []string  `json:"args"`
+	Returns    []string  `json:"returns"`
+	Source     string    `json:"source"`
+	CallSite   string    `json:"callSite,omitempty"`
+	DurationMS float64   `json:"durationMs"`
+}
+
+// LogEvents writes every call intercepted by a mock to w as a line of JSON, with the mock's alias, the method, the code
+// of its arguments and return values, whether the values returned were real, mocked, filled or zero, how long the call
+// took, and the goroutine, test and code that made it.  Passing nil stops logging events
+func LogEvents(w io.Writer) {
+	eventLogLock.Lock()
+	defer eventLogLock.Unlock()
+	eventLog = w
+}
+
+// logEvent writes a call intercepted by a mock to the event log, if there is one
+func logEvent(reporter gomock.TestReporter, mockAlias string, methodName string, args []reflect.Value, returns []reflect.Value, source string, duration time.Duration) {
+	eventLogLock.Lock()
+	defer eventLogLock.Unlock()
+	if eventLog == nil {
+		return
+	}
+	event := callEvent{
+		Time:       time.Now(),
+		Test:       testName(reporter),
+		Goroutine:  goroutineID(),
+		Alias:      mockAlias,
+		Method:     methodName,
+		Args:       renderEach(redact(args)),
+		Returns:    renderEach(redact(returns)),
+		Source:     source,
+		CallSite:   callSite(),
+		DurationMS: float64(duration) / float64(time.Millisecond),
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		emitf("vmockhelper: could not log %s.%s: %s", mockAlias, methodName, err.Error())
+		return
+	}
+	if _, err := eventLog.Write(append(line, '\n')); err != nil {
+		emitf("vmockhelper: could not log %s.%s: %s", mockAlias, methodName, err.Error())
+	}
+}
+
+// testName returns the name of the test reported to by reporter, or an empty string if it is not a test
+func testName(reporter gomock.TestReporter) string {
+	if named, ok := reporter.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// renderEach returns the code of each value, with contexts matching any context
+func renderEach(values []reflect.Value) []string {
+	codes := []string{}
+	r := &renderer{}
+	for _, value := range values {
+		if value.IsValid() && isContext(value) {
+			codes = append(codes, r.ident(gomockPath, "Any")+"()")
+			continue
+		}
+		codes = append(codes, r.code(value))
+	}
+	return codes
+}
+
+// goroutineID returns the ID of the current goroutine, as shown at the top of its stack trace
+func goroutineID() int64 {
+	stack := make([]byte, 64)
+	stack = stack[:runtime.Stack(stack, false)]
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i >= 0 {
+		stack = stack[:i]
+	}
+	id, _ := strconv.ParseInt(string(stack), 10, 64)
+	return id
+}
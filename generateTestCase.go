@@ -1,91 +1,308 @@
 package vmockhelper
 
 import (
-	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/short-hop/vrender"
-	"github.com/vendasta/gosdks/logging"
+	"sync"
 )
 
 // Record will begin to record mock calls
 func Record() {
+	recordingLock.Lock()
+	defer recordingLock.Unlock()
+	record = true
+}
+
+// StartCase begins a new test case named name, starting to record if Record has not been called.  Calls recorded after
+// it belong to the new case, so that a test run exercising several scenarios prints a case for each of them.  A case is
+// printed even if no calls are recorded in it
+func StartCase(name string) {
+	recordingLock.Lock()
+	defer recordingLock.Unlock()
 	record = true
+	startedCases = append(startedCases, name)
+	currentCase = len(startedCases) - 1
 }
 
-// Clear will clear all recorded mock calls
+// Clear will clear all recorded mock calls, and forget the pseudonyms given to redacted values
 func Clear() {
+	recordingLock.Lock()
 	record = false
 	recordedCalls = []Call{}
+	startedCases = []string{""}
+	currentCase = 0
+	recordedPerMethod = map[string]int{}
+	unrecorded = map[string]int{}
+	reserved = 0
+	recordingLock.Unlock()
+	pseudonymsLock.Lock()
+	pseudonyms = map[string]int{}
+	pseudonymsLock.Unlock()
 }
 
+// recordingLock guards everything about recording: whether calls are recorded, the calls recorded and the cases they
+// belong to, and the limits and counts of LimitRecordedCalls
+var recordingLock sync.Mutex
+
 var record bool
 var recordedCalls []Call
 
+// startedCases holds the name of every test case started with StartCase, in order, after the unnamed case holding the
+// calls recorded before the first of them.  currentCase is the index of the case calls are recorded in
+var startedCases = []string{""}
+var currentCase int
+
+// recording returns whether calls are being recorded
+func recording() bool {
+	recordingLock.Lock()
+	defer recordingLock.Unlock()
+	return record
+}
+
+// recorded returns the calls recorded so far
+func recorded() []Call {
+	recordingLock.Lock()
+	defer recordingLock.Unlock()
+	return append([]Call{}, recordedCalls...)
+}
+
 type Call struct {
 	method  string
 	alias   string
 	args    []reflect.Value
 	returns []reflect.Value
+	// test is the name of the test that made the call, if it is known
+	test string
+	// source is where the values returned came from: real, mocked, filled or zero
+	source string
+	// testCase is the index in startedCases of the case the call was recorded in
+	testCase int
+}
+
+// recordedCase is the calls recorded for one test case
+type recordedCase struct {
+	name  string
+	calls []Call
 }
 
-// PrintTestCase takes recorded calls and prints a test case that can be used to test the same functionality
+// caseField is a field of the printed test case type, holding an argument or return value of a recorded call
+type caseField struct {
+	name     string
+	value    reflect.Value
+	returned bool
+}
+
+// PrintTestCase takes recorded calls and prints a test case that can be used to test the same functionality.  Calls
+// recorded after StartCase are printed as a separate case with a name.  Values are only rendered when it is called
 func PrintTestCase() {
-	template := `type testCase struct {
+	template := `{{imports}}{{unrecorded}}type testCase struct {
 {{caseType}}}
-cases := []*testCase{
+{{variables}}cases := []*testCase{
 {{cases}}
 }`
-	template = strings.Replace(template, "{{caseType}}", generateTestCaseType(), -1)
-	template = strings.Replace(template, "{{cases}}", generateTestCase(), -1)
-	logging.Alertf(context.Background(), template)
+	recordingLock.Lock()
+	cases := groupCases(recordedCalls, startedCases)
+	unrecordedCalls := unrecordedNote()
+	recordingLock.Unlock()
+	r := &renderer{imports: newImportSet("")}
+	template = strings.Replace(template, "{{caseType}}", generateTestCaseType(r, cases), -1)
+	// Values are only correlated within a case, so that each case can be changed without affecting the others
+	var declarations string
+	var generated string
+	taken := map[string]bool{}
+	for i, c := range cases {
+		r.variables = correlateValues(c, i+1, taken)
+		declarations += generateVariables(r.variables)
+		generated += generateTestCase(r, c, named(cases))
+	}
+	template = strings.Replace(template, "{{variables}}", declarations, -1)
+	template = strings.Replace(template, "{{cases}}", generated, -1)
+	template = strings.Replace(template, "{{imports}}", r.imports.block(), -1)
+	template = strings.Replace(template, "{{unrecorded}}", unrecordedCalls, -1)
+	emitRequested(template)
 }
 
-func generateTestCaseType() string {
-	caseType := ""
-	for _, call := range recordedCalls {
+// groupCases splits calls into the test cases named by started they were recorded in, in the order the cases were
+// started.  The unnamed case holding calls recorded before the first named case is left out if it has no calls
+func groupCases(calls []Call, started []string) []recordedCase {
+	cases := make([]recordedCase, len(started))
+	for i, name := range started {
+		cases[i].name = name
+	}
+	for _, call := range calls {
+		cases[call.testCase].calls = append(cases[call.testCase].calls, call)
+	}
+	if len(cases) > 1 && len(cases[0].calls) == 0 {
+		cases = cases[1:]
+	}
+	return cases
+}
+
+// named returns whether any of cases was given a name with StartCase
+func named(cases []recordedCase) bool {
+	for _, c := range cases {
+		if c.name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// caseFields returns the fields of the test case type holding the arguments and return values of every call in a
+// case
+func caseFields(c recordedCase) []caseField {
+	var fields []caseField
+	calls := map[string]int{}
+	for _, call := range c.calls {
+		calls[call.alias+"."+call.method]++
+		fields = append(fields, callFields(call, calls[call.alias+"."+call.method])...)
+	}
+	return fields
+}
+
+// callFields returns the fields of the test case type holding the arguments and return values of a call, skipping
+// contexts.  number counts the calls to the same method in the case, and is part of the name of every call after the
+// first, so that a method called twice does not declare the same field twice
+func callFields(call Call, number int) []caseField {
+	prefix := call.alias + call.method
+	if number > 1 {
+		prefix += fmt.Sprintf("Call%d", number)
+	}
+	var fields []caseField
+	for _, returned := range []bool{false, true} {
+		direction, values := "In", call.args
+		if returned {
+			direction, values = "Out", call.returns
+		}
 		indexOffset := 1
-		for i, arg := range call.args {
-			if isContext(arg) {
+		for i, value := range values {
+			if isContext(value) {
 				indexOffset--
 				continue
 			}
-			caseType += fmt.Sprintf("\t%s%sIn%d %s\n", call.alias, call.method, i+indexOffset, arg.Type().String())
+			name := fmt.Sprintf("%s%s%d", prefix, direction, i+indexOffset)
+			fields = append(fields, caseField{name: name, value: value, returned: returned})
 		}
-		indexOffset = 1
-		for i, arg := range call.returns {
-			if isContext(arg) {
-				indexOffset--
+	}
+	return fields
+}
+
+func generateTestCaseType(r *renderer, cases []recordedCase) string {
+	caseType := ""
+	if named(cases) {
+		caseType += "\tname string\n"
+	}
+	declared := map[string]bool{}
+	for _, c := range cases {
+		for _, field := range caseFields(c) {
+			if declared[field.name] {
 				continue
 			}
-			caseType += fmt.Sprintf("\t%s%sOut%d %s\n", call.alias, call.method, i+indexOffset, arg.Type().String())
+			declared[field.name] = true
+			caseType += fmt.Sprintf("\t%s %s\n", field.name, r.typeString(field.value.Type()))
 		}
 	}
 	return caseType
 }
 
-func generateTestCase() string {
+func generateTestCase(r *renderer, c recordedCase, named bool) string {
 	testCase := "{\n"
-	for _, call := range recordedCalls {
-		indexOffset := 1
-		for i, arg := range call.args {
-			if isContext(arg) {
-				indexOffset--
-				continue
+	if named {
+		testCase += fmt.Sprintf("\tname: %s,\n", strconv.Quote(c.name))
+	}
+	for _, field := range caseFields(c) {
+		testCase += fmt.Sprintf("\t%s: %s,\n", field.name, r.code(field.value))
+	}
+	testCase += fmt.Sprintln("},")
+	return testCase
+}
+
+// minCorrelatedLength is the shortest string that is correlated between calls.  Shorter strings, like country codes,
+// are likely to match by coincidence
+const minCorrelatedLength = 6
+
+// correlateValues finds strings returned by a call recorded in a case that are passed as an argument to a later call in
+// the same case, like an ID returned by Create and then passed to Get.  It returns a map of each of those strings to
+// the name of a variable named after where the string was first returned.  Names in taken are used by other cases, and
+// are suffixed with the number of the case instead
+func correlateValues(c recordedCase, number int, taken map[string]bool) map[string]string {
+	returned := map[string]string{}
+	names := map[string]bool{}
+	variables := map[string]string{}
+	for _, field := range caseFields(c) {
+		if !field.returned {
+			walkStrings(field.value, "", map[uintptr]bool{}, func(value string, _ string) {
+				if name, ok := returned[value]; ok {
+					variables[value] = name
+				}
+			})
+			continue
+		}
+		walkStrings(field.value, field.name, map[uintptr]bool{}, func(value string, path string) {
+			if _, ok := returned[value]; ok {
+				return
+			}
+			name := path
+			if taken[name] {
+				path += fmt.Sprintf("Case%d", number)
+				name = path
 			}
-			testCase += fmt.Sprintf("\t%s%sIn%d: %s,\n", call.alias, call.method, i+indexOffset, vrender.Render(arg.Interface()))
+			for suffix := 2; taken[name] || names[name]; suffix++ {
+				name = path + strconv.Itoa(suffix)
+			}
+			names[name] = true
+			returned[value] = name
+		})
+	}
+	for _, name := range variables {
+		taken[name] = true
+	}
+	return variables
+}
+
+// walkStrings calls visit with every string long enough to be correlated within v, along with a name for it built
+// from path and the fields and indexes leading to it
+func walkStrings(v reflect.Value, path string, visiting map[uintptr]bool, visit func(value string, path string)) {
+	if !v.IsValid() || !v.CanInterface() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if v.Type() == stringType && len(v.String()) >= minCorrelatedLength {
+			visit(v.String(), path)
 		}
-		indexOffset = 1
-		for i, arg := range call.returns {
-			if isContext(arg) {
-				indexOffset--
-				continue
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			if visiting[v.Pointer()] {
+				return
 			}
-			testCase += fmt.Sprintf("\t%s%sOut%d: %s,\n", call.alias, call.method, i+indexOffset, vrender.Render(arg.Interface()))
+			visiting[v.Pointer()] = true
+			defer delete(visiting, v.Pointer())
+		}
+		walkStrings(v.Elem(), path, visiting, visit)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			walkStrings(v.Field(i), path+v.Type().Field(i).Name, visiting, visit)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), path+strconv.Itoa(i), visiting, visit)
 		}
 	}
-	testCase += fmt.Sprintln("},")
-	return testCase
+}
+
+// generateVariables declares a variable for each correlated value
+func generateVariables(variables map[string]string) string {
+	var declarations []string
+	for value, name := range variables {
+		declarations = append(declarations, fmt.Sprintf("%s := %s\n", name, strconv.Quote(value)))
+	}
+	sort.Strings(declarations)
+	return strings.Join(declarations, "")
 }
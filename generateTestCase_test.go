@@ -0,0 +1,124 @@
+package vmockhelper
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordWithRealService records the calls made by calls to a mock relaying to a fake service, returning what
+// PrintTestCase prints for them
+func recordWithRealService(t *testing.T, calls func(mock *MockTestService)) string {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	Clear()
+	t.Cleanup(Clear)
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, &fakeService{name: "Alice"}, "svc")
+	Record()
+	calls(mock)
+	buffer.Reset()
+	PrintTestCase()
+	return buffer.String()
+}
+
+func TestPrintTestCaseCorrelatesReturnedValues(t *testing.T) {
+	printed := recordWithRealService(t, func(mock *MockTestService) {
+		id, _ := mock.Create(context.Background(), &testThing{Name: "widget"})
+		_, _ = mock.Get(context.Background(), id)
+	})
+
+	assert.Contains(t, printed, `svcCreateOut1 := "ID-widget"`)
+	assert.Contains(t, printed, "\tsvcCreateOut1: svcCreateOut1,\n")
+	assert.Contains(t, printed, "\tsvcGetIn1: svcCreateOut1,\n")
+	assert.Contains(t, printed, "ID:svcCreateOut1,")
+	assert.NotContains(t, printed, `"Alice" :=`, "values that are only returned are not correlated")
+}
+
+func TestPrintTestCaseDoesNotCorrelateShortValues(t *testing.T) {
+	printed := recordWithRealService(t, func(mock *MockTestService) {
+		id, _ := mock.Create(context.Background(), &testThing{Name: "a"})
+		_, _ = mock.Get(context.Background(), id)
+	})
+
+	assert.NotContains(t, printed, "svcCreateOut1 :=")
+	assert.Contains(t, printed, "\tsvcGetIn1: \"ID-a\",\n")
+}
+
+func TestStartCasePrintsACaseForEachScenario(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	Clear()
+	defer Clear()
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, &fakeService{name: "Alice"}, "svc")
+
+	_, _ = mock.Get(context.Background(), "not recorded")
+	StartCase("found")
+	_, _ = mock.Get(context.Background(), "1")
+	StartCase("created")
+	_, _ = mock.Create(context.Background(), &testThing{Name: "new"})
+	StartCase("nothing")
+	buffer.Reset()
+	PrintTestCase()
+	printed := buffer.String()
+
+	assert.Contains(t, printed, "type testCase struct {\n\tname string\n\tsvcGetIn1 string\n")
+	assert.Contains(t, printed, "{\n\tname: \"found\",\n\tsvcGetIn1: \"1\",\n")
+	assert.Contains(t, printed, "{\n\tname: \"created\",\n\tsvcCreateIn1: ")
+	assert.Contains(t, printed, "{\n\tname: \"nothing\",\n},")
+	assert.NotContains(t, printed, "not recorded", "StartCase starts recording")
+	assert.NotContains(t, printed, `name: ""`, "the unnamed case is left out when it has no calls")
+}
+
+func TestStartCaseAfterRecord(t *testing.T) {
+	printed := recordWithRealService(t, func(mock *MockTestService) {
+		_, _ = mock.Get(context.Background(), "first")
+		StartCase("second")
+		_, _ = mock.Get(context.Background(), "second")
+	})
+
+	assert.Contains(t, printed, "{\n\tname: \"\",\n\tsvcGetIn1: \"first\",\n")
+	assert.Contains(t, printed, "{\n\tname: \"second\",\n\tsvcGetIn1: \"second\",\n")
+}
+
+func TestPrintTestCaseWithoutStartCase(t *testing.T) {
+	printed := recordWithRealService(t, func(mock *MockTestService) {
+		_, _ = mock.Get(context.Background(), "1")
+	})
+
+	assert.NotContains(t, printed, "name")
+	assert.Contains(t, printed, "cases := []*testCase{\n{\n\tsvcGetIn1: \"1\",\n")
+}
+
+func TestPrintTestCaseNumbersRepeatedCalls(t *testing.T) {
+	printed := recordWithRealService(t, func(mock *MockTestService) {
+		_, _ = mock.Get(context.Background(), "1")
+		_, _ = mock.List(context.Background(), "2", "3")
+		_, _ = mock.Get(context.Background(), "4")
+		_, _ = mock.Get(context.Background(), "5")
+	})
+
+	assert.Contains(t, printed, "\tsvcGetIn1: \"1\",\n")
+	assert.Contains(t, printed, "\tsvcListIn1: []string{\"2\", \"3\"},\n")
+	assert.Contains(t, printed, "\tsvcGetCall2In1: \"4\",\n")
+	assert.Contains(t, printed, "\tsvcGetCall3In1: \"5\",\n")
+	assert.Contains(t, printed, "\tsvcGetCall3Out2 error\n")
+}
+
+func TestPrintTestCaseDeclaresFieldsOnceAcrossCases(t *testing.T) {
+	printed := recordWithRealService(t, func(mock *MockTestService) {
+		StartCase("one")
+		_, _ = mock.Get(context.Background(), "1")
+		StartCase("two")
+		_, _ = mock.Get(context.Background(), "2")
+		_, _ = mock.Get(context.Background(), "3")
+	})
+
+	assert.Equal(t, 1, strings.Count(printed, "\tsvcGetIn1 string\n"))
+	assert.Equal(t, 1, strings.Count(printed, "\tsvcGetCall2In1 string\n"))
+	assert.Contains(t, printed, "\tname: \"two\",\n\tsvcGetIn1: \"2\",\n")
+}
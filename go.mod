@@ -8,4 +8,7 @@ require (
 	github.com/stretchr/testify v1.7.5
 	github.com/vendasta/gosdks/config v1.1.0
 	github.com/vendasta/gosdks/logging v1.15.0
+	github.com/vendasta/gosdks/verrors v1.4.0
+	google.golang.org/grpc v1.31.0
+	google.golang.org/protobuf v1.25.0
 )
@@ -0,0 +1,43 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vendasta/gosdks/config"
+)
+
+// allowRealEnvVar is the environment variable that allows UseRealAndPrintExpected to call real services in CI
+const allowRealEnvVar = "VMOCKHELPER_ALLOW_REAL"
+
+// ciEnvVars are environment variables set by CI systems
+var ciEnvVars = []string{"CI", "CONTINUOUS_INTEGRATION", "BUILD_ID", "BUILD_NUMBER", "JENKINS_URL", "GITHUB_ACTIONS", "GITLAB_CI"}
+
+// credentialsEnv is the environment GetCredentials was last called with, or nil if it has not been called
+var credentialsEnv *config.Env
+
+// realRelayBlocked returns the reason calls should not be relayed to a real service, or an empty string if they can
+// be.  Calls to production are never relayed.  Calls made in CI are only relayed if the allowRealEnvVar environment
+// variable is set or the package was built with the vmockhelper_real tag
+func realRelayBlocked() string {
+	if credentialsEnv != nil && *credentialsEnv == config.Prod {
+		return "GetCredentials was called with config.Prod"
+	}
+	if env, err := config.GetEnvErr(os.Getenv("ENVIRONMENT")); err == nil && env == config.Prod {
+		return "ENVIRONMENT is set to prod"
+	}
+	if allowRealByTag || isSet(os.Getenv(allowRealEnvVar)) {
+		return ""
+	}
+	for _, name := range ciEnvVars {
+		if isSet(os.Getenv(name)) {
+			return fmt.Sprintf("the %s environment variable says this is running in CI. Set %s=true to allow it", name, allowRealEnvVar)
+		}
+	}
+	return ""
+}
+
+// isSet returns whether an environment variable holds a value other than empty, false or 0
+func isSet(value string) bool {
+	return value != "" && value != "false" && value != "0"
+}
@@ -0,0 +1,72 @@
+package vmockhelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vendasta/gosdks/config"
+)
+
+// skipIfAllowedByTag skips a test of the CI guard when the package was built with the vmockhelper_real tag, which
+// turns it off
+func skipIfAllowedByTag(t *testing.T) {
+	if allowRealByTag {
+		t.Skip("built with the vmockhelper_real tag, which allows real calls in CI")
+	}
+}
+
+// outsideCI unsets every environment variable the guard looks at for the rest of the test
+func outsideCI(t *testing.T) {
+	for _, name := range append(ciEnvVars, "ENVIRONMENT", allowRealEnvVar) {
+		t.Setenv(name, "")
+	}
+}
+
+func TestRealRelayBlocked(t *testing.T) {
+	skipIfAllowedByTag(t)
+	outsideCI(t)
+	assert.Empty(t, realRelayBlocked())
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.Equal(t, "the GITHUB_ACTIONS environment variable says this is running in CI. Set VMOCKHELPER_ALLOW_REAL=true to allow it", realRelayBlocked())
+
+	t.Setenv(allowRealEnvVar, "false")
+	assert.NotEmpty(t, realRelayBlocked(), "false does not allow calls in CI")
+
+	t.Setenv(allowRealEnvVar, "true")
+	assert.Empty(t, realRelayBlocked())
+
+	t.Setenv("ENVIRONMENT", "prod")
+	assert.Equal(t, "ENVIRONMENT is set to prod", realRelayBlocked(), "production is blocked even when allowed")
+}
+
+func TestRealRelayBlockedAfterGetCredentialsForProd(t *testing.T) {
+	outsideCI(t)
+	t.Setenv(allowRealEnvVar, "true")
+	env := config.Prod
+	credentialsEnv = &env
+	defer func() {
+		credentialsEnv = nil
+	}()
+
+	assert.Equal(t, "GetCredentials was called with config.Prod", realRelayBlocked())
+}
+
+func TestUseRealAndPrintExpectedInCI(t *testing.T) {
+	skipIfAllowedByTag(t)
+	outsideCI(t)
+	t.Setenv("CI", "1")
+	buffer := useBuffer(t)
+	real := &fakeService{name: "Alice"}
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, real, "svc")
+
+	thing, err := mock.Get(context.Background(), "1")
+
+	assert.Nil(t, thing)
+	assert.NoError(t, err)
+	assert.Empty(t, real.called())
+	assert.Contains(t, buffer.String(), "!!! vmockhelper: svc will not call the real service because the CI environment variable says this is running in CI")
+}
@@ -0,0 +1,143 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// importSet collects the packages referenced by rendered code, and gives each of them an alias that does not collide
+// with any other package in the set
+type importSet struct {
+	// local is the path of the package the code is written in, whose identifiers are not qualified
+	local string
+	// aliases maps each package path to its alias
+	aliases map[string]string
+	// paths maps each alias to its package path
+	paths map[string]string
+	// names maps each package path to the name declared by the package
+	names map[string]string
+}
+
+func newImportSet(local string) *importSet {
+	return &importSet{
+		local:   local,
+		aliases: map[string]string{},
+		paths:   map[string]string{},
+		names:   map[string]string{},
+	}
+}
+
+// qualify returns the identifier for name in the package at pkgPath, adding the package to the set.  pkgName is the
+// name declared by the package.  A nil set qualifies identifiers with the package name
+func (s *importSet) qualify(pkgPath string, pkgName string, name string) string {
+	if pkgPath == "" {
+		return name
+	}
+	if s == nil {
+		return pkgName + "." + name
+	}
+	if pkgPath == s.local {
+		return name
+	}
+	return s.alias(pkgPath, pkgName) + "." + name
+}
+
+// alias returns the alias of the package at pkgPath, choosing one if the package is not yet in the set
+func (s *importSet) alias(pkgPath string, pkgName string) string {
+	if alias, ok := s.aliases[pkgPath]; ok {
+		return alias
+	}
+	candidates := []string{pkgName}
+	if parent := identifier(path.Base(path.Dir(pkgPath))); parent != "" && unicode.IsLetter(rune(parent[0])) {
+		candidates = append(candidates, parent+pkgName)
+	}
+	alias := ""
+	for _, candidate := range candidates {
+		if _, taken := s.paths[candidate]; !taken {
+			alias = candidate
+			break
+		}
+	}
+	for i := 2; alias == ""; i++ {
+		candidate := pkgName + strconv.Itoa(i)
+		if _, taken := s.paths[candidate]; !taken {
+			alias = candidate
+		}
+	}
+	s.aliases[pkgPath] = alias
+	s.paths[alias] = pkgPath
+	s.names[pkgPath] = pkgName
+	return alias
+}
+
+// specs returns the import spec of every package in the set that is not in printed, sorted by path
+func (s *importSet) specs(printed map[string]bool) []string {
+	var specs []string
+	for pkgPath, alias := range s.aliases {
+		if printed[pkgPath] {
+			continue
+		}
+		if alias == s.names[pkgPath] && alias == path.Base(pkgPath) {
+			specs = append(specs, strconv.Quote(pkgPath))
+		} else {
+			specs = append(specs, fmt.Sprintf("%s %s", alias, strconv.Quote(pkgPath)))
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return unquotedPath(specs[i]) < unquotedPath(specs[j])
+	})
+	return specs
+}
+
+// block returns an import block containing every package in the set, or an empty string if the set is empty
+func (s *importSet) block() string {
+	return importBlock(s.specs(nil))
+}
+
+// importBlock returns an import block containing specs, or an empty string if there are none.  Like goimports, the
+// standard library is grouped before every other package
+func importBlock(specs []string) string {
+	if len(specs) == 0 {
+		return ""
+	}
+	var std []string
+	var other []string
+	for _, spec := range specs {
+		if strings.Contains(strings.Split(unquotedPath(spec), "/")[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	groups := []string{}
+	for _, group := range [][]string{std, other} {
+		if len(group) > 0 {
+			groups = append(groups, strings.Join(group, "\n\t"))
+		}
+	}
+	return fmt.Sprintf("import (\n\t%s\n)\n", strings.Join(groups, "\n\n\t"))
+}
+
+// unquotedPath returns the path of an import spec
+func unquotedPath(spec string) string {
+	quoted := spec[strings.Index(spec, `"`):]
+	unquoted, err := strconv.Unquote(quoted)
+	if err != nil {
+		return quoted
+	}
+	return unquoted
+}
+
+// identifier removes characters that are not allowed in a Go identifier
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
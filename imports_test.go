@@ -0,0 +1,58 @@
+package vmockhelper
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestImportSetAliasesCollidingPackages(t *testing.T) {
+	s := newImportSet("example.com/local")
+
+	assert.Equal(t, "v1.Listing", s.qualify("example.com/listing/v1", "v1", "Listing"))
+	assert.Equal(t, "accountv1.Account", s.qualify("example.com/account/v1", "v1", "Account"))
+	assert.Equal(t, "v12.Other", s.qualify("example.com/other/account/v1", "v1", "Other"))
+	assert.Equal(t, "v1.Status", s.qualify("example.com/listing/v1", "v1", "Status"), "a package keeps its alias")
+	assert.Equal(t, "Local", s.qualify("example.com/local", "local", "Local"), "the local package is not qualified")
+	assert.Equal(t, "string", s.qualify("", "", "string"))
+
+	assert.Equal(t, `import (
+	accountv1 "example.com/account/v1"
+	"example.com/listing/v1"
+	v12 "example.com/other/account/v1"
+)
+`, s.block())
+}
+
+func TestImportSetNamesPackagesWhoseNameDiffersFromTheirPath(t *testing.T) {
+	s := newImportSet("")
+	s.qualify("example.com/go-listing", "listing", "Listing")
+	s.qualify("time", "time", "Duration")
+
+	assert.Equal(t, `import (
+	"time"
+
+	listing "example.com/go-listing"
+)
+`, s.block())
+}
+
+func TestRendererCollectsImports(t *testing.T) {
+	r := &renderer{imports: newImportSet("")}
+
+	assert.Equal(t, "*timestamppb.Timestamp", r.typeString(reflect.TypeOf(&timestamppb.Timestamp{})))
+	assert.Equal(t, "map[string]time.Duration", r.typeString(reflect.TypeOf(map[string]time.Duration{})))
+	assert.Equal(t, `import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+`, r.imports.block())
+}
+
+func TestEmptyImportSet(t *testing.T) {
+	assert.Empty(t, newImportSet("").block())
+}
@@ -0,0 +1,67 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxRecordedPerMethod and maxRecorded limit how many calls are recorded for each method and in total, or are 0 for no
+// limit
+var maxRecordedPerMethod int
+var maxRecorded int
+
+// recordedPerMethod counts the calls recorded for each alias.Method, and unrecorded counts the calls that were not
+// recorded because of a limit
+var recordedPerMethod = map[string]int{}
+var unrecorded = map[string]int{}
+
+// reserved counts the calls that are being or have been recorded, since a call is only added to recordedCalls once it
+// returns
+var reserved int
+
+// LimitRecordedCalls keeps memory bounded in tests that make many calls, by only recording the first perMethod calls
+// to each method, and the first total calls altogether.  A limit of 0 means no limit.  Calls over a limit are counted,
+// and the counts are printed with PrintTestCase and a warning the first time a method goes over
+func LimitRecordedCalls(perMethod int, total int) {
+	recordingLock.Lock()
+	defer recordingLock.Unlock()
+	maxRecordedPerMethod = perMethod
+	maxRecorded = total
+}
+
+// reserveRecording returns whether a call to a method should be recorded and the index of the case it is recorded in,
+// counting it against the limits if it should be recorded, and as unrecorded if recording is limited
+func reserveRecording(mockAlias string, methodName string) (bool, int) {
+	key := mockAlias + "." + methodName
+	recordingLock.Lock()
+	if !record {
+		recordingLock.Unlock()
+		return false, 0
+	}
+	if (maxRecordedPerMethod > 0 && recordedPerMethod[key] >= maxRecordedPerMethod) || (maxRecorded > 0 && reserved >= maxRecorded) {
+		unrecorded[key]++
+		first := unrecorded[key] == 1
+		recordingLock.Unlock()
+		if first {
+			emitf("vmockhelper: the limit set by LimitRecordedCalls was reached, calls to %s are no longer recorded", key)
+		}
+		return false, 0
+	}
+	recordedPerMethod[key]++
+	reserved++
+	testCase := currentCase
+	recordingLock.Unlock()
+	return true, testCase
+}
+
+// unrecordedNote returns a comment listing how many calls to each method were not recorded, or an empty string if
+// every call was.  recordingLock must be held
+func unrecordedNote() string {
+	var lines []string
+	for key, count := range unrecorded {
+		lines = append(lines, fmt.Sprintf("// %d calls to %s were not recorded because of LimitRecordedCalls\n", count, key))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,62 @@
+package vmockhelper
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitRecordedCalls(t *testing.T) {
+	requested := &BufferSink{}
+	SetSink(DiscardSink(requested))
+	defer SetSink(nil)
+	Clear()
+	defer Clear()
+	LimitRecordedCalls(2, 3)
+	defer LimitRecordedCalls(0, 0)
+	mock := NewMockTestService(gomock.NewController(t))
+	MockCallsAndPrintExpected(mock, "svc")
+	Record()
+
+	for i := 0; i < 3; i++ {
+		_, _ = mock.Get(context.Background(), "1")
+	}
+	for i := 0; i < 2; i++ {
+		_, _ = mock.Create(context.Background(), &testThing{})
+	}
+
+	calls := recorded()
+	assert.Len(t, calls, 3)
+	assert.Equal(t, []string{"Get", "Get", "Create"}, []string{calls[0].method, calls[1].method, calls[2].method})
+	PrintTestCase()
+	assert.Contains(t, requested.String(), "// 1 calls to svc.Create were not recorded because of LimitRecordedCalls\n// 1 calls to svc.Get were not recorded because of LimitRecordedCalls\n")
+}
+
+func TestLimitRecordedCallsConcurrently(t *testing.T) {
+	SetSink(DiscardSink())
+	defer SetSink(nil)
+	Clear()
+	defer Clear()
+	LimitRecordedCalls(0, 50)
+	defer LimitRecordedCalls(0, 0)
+	mock := NewMockTestService(gomock.NewController(t))
+	MockCallsAndPrintExpected(mock, "svc")
+	Record()
+
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 5; j++ {
+				_, _ = mock.Get(context.Background(), "1")
+			}
+		}()
+	}
+	wait.Wait()
+
+	assert.Len(t, recorded(), 50)
+}
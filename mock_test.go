@@ -0,0 +1,174 @@
+package vmockhelper
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testThing is what testService stores
+type testThing struct {
+	ID   string
+	Name string
+	Tags []string
+}
+
+// testService is the interface the tests mock
+type testService interface {
+	Get(ctx context.Context, id string) (*testThing, error)
+	Create(ctx context.Context, thing *testThing) (string, error)
+	List(ctx context.Context, ids ...string) ([]*testThing, error)
+}
+
+// fakeService stands in for a real service, counting the calls made to it
+type fakeService struct {
+	lock  sync.Mutex
+	calls []string
+	// name is the name of every thing returned
+	name string
+	// failures is how many calls fail with Unavailable before one succeeds
+	failures int
+	// delay is how long each call takes
+	delay time.Duration
+}
+
+func (s *fakeService) call(ctx context.Context, method string) error {
+	s.lock.Lock()
+	s.calls = append(s.calls, method)
+	failed := s.failures > 0
+	if failed {
+		s.failures--
+	}
+	s.lock.Unlock()
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+		}
+	}
+	if failed {
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	return nil
+}
+
+// called returns the methods called so far
+func (s *fakeService) called() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.calls...)
+}
+
+func (s *fakeService) Get(ctx context.Context, id string) (*testThing, error) {
+	if err := s.call(ctx, "Get"); err != nil {
+		return nil, err
+	}
+	return &testThing{ID: id, Name: s.name}, nil
+}
+
+func (s *fakeService) Create(ctx context.Context, thing *testThing) (string, error) {
+	if err := s.call(ctx, "Create"); err != nil {
+		return "", err
+	}
+	return "ID-" + thing.Name, nil
+}
+
+func (s *fakeService) List(ctx context.Context, ids ...string) ([]*testThing, error) {
+	if err := s.call(ctx, "List"); err != nil {
+		return nil, err
+	}
+	var things []*testThing
+	for _, id := range ids {
+		things = append(things, &testThing{ID: id, Name: s.name})
+	}
+	return things, nil
+}
+
+// MockTestService is a mock of testService, written the way mockgen writes mocks
+type MockTestService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTestServiceMockRecorder
+}
+
+// MockTestServiceMockRecorder is the mock recorder for MockTestService
+type MockTestServiceMockRecorder struct {
+	mock *MockTestService
+}
+
+// NewMockTestService creates a new mock instance
+func NewMockTestService(ctrl *gomock.Controller) *MockTestService {
+	mock := &MockTestService{ctrl: ctrl}
+	mock.recorder = &MockTestServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTestService) EXPECT() *MockTestServiceMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method
+func (m *MockTestService) Get(ctx context.Context, id string) (*testThing, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*testThing)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockTestServiceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTestService)(nil).Get), ctx, id)
+}
+
+// Create mocks base method
+func (m *MockTestService) Create(ctx context.Context, thing *testThing) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, thing)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockTestServiceMockRecorder) Create(ctx, thing interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTestService)(nil).Create), ctx, thing)
+}
+
+// List mocks base method
+func (m *MockTestService) List(ctx context.Context, ids ...string) ([]*testThing, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range ids {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "List", varargs...)
+	ret0, _ := ret[0].([]*testThing)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockTestServiceMockRecorder) List(ctx interface{}, ids ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, ids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTestService)(nil).List), varargs...)
+}
+
+// useBuffer sends everything printed during the test to a buffer, going back to logging when the test ends
+func useBuffer(t interface{ Cleanup(func()) }) *BufferSink {
+	buffer := &BufferSink{}
+	SetSink(buffer)
+	t.Cleanup(func() {
+		SetSink(nil)
+	})
+	return buffer
+}
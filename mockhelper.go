@@ -3,22 +3,49 @@ package vmockhelper
 import (
 	"context"
 	"fmt"
+	"go/format"
 	"os"
 	"os/exec"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/short-hop/vrender"
 	"github.com/vendasta/gosdks/config"
-	"github.com/vendasta/gosdks/logging"
 )
 
 const mockFMT = "\n%s.EXPECT().%s(%s).Return(%s)\n"
 
+var hoistThreshold int
+
+// expectedImports holds the imports of the expectations printed by each test, by test name
+var expectedImports = map[string]*testImports{}
+var expectedImportsLock sync.Mutex
+
+// testImports holds the aliases of the packages referenced by the expectations printed in one test, so that every
+// expectation in the test refers to a package by the same alias.  printed holds the paths that have already been
+// printed in the test
+type testImports struct {
+	set     *importSet
+	printed map[string]bool
+	// hoisted counts the printed expected calls of each mock method that hoisted values, to number their variables
+	hoisted map[string]int
+}
+
+// HoistLargeValues makes printed expectations declare any argument or return value whose code is longer than
+// minLength characters as a variable, formatted across multiple lines, and reference that variable in the expected
+// call.  The variables of later calls to the same method in a test are numbered, like agMockGetCall2Out1, so they do
+// not collide.  A minLength of 0 turns this off
+func HoistLargeValues(minLength int) {
+	hoistThreshold = minLength
+}
+
 // GetCredentials sets environment variables required to initialize microservice Go SDKs locally.  Should be called at
 // the beginning of a test function before any test cases are run
 func GetCredentials(env config.Env) error {
+	credentialsEnv = &env
 	err := os.Setenv("ENVIRONMENT", env.Name())
 	if err != nil {
 		return err
@@ -42,18 +69,58 @@ func NewFilledType(t reflect.Type) reflect.Value {
 // optionally include a list of response arguments for the mock call to return, but it will try to return those same
 // arguments for every method, so this will not work in all cases
 func MockCallsAndPrintExpected(gomockObject interface{}, mockAlias string, mockResponseArgs ...interface{}) {
+	reporter := testReporter(gomockObject)
+	handleAllMethods(gomockObject, mockAlias, func(methodName string, methodType reflect.Type, args []reflect.Value) ([]reflect.Value, string) {
+		return mockCall(reporter, mockAlias, methodName, methodType, args, mockResponseArgs, "")
+	})
+}
+
+// UseRealAndPrintExpected takes in a gomock object and an instance of the actual service being mocked.  When a mock
+// method is called, it calls the same method on the real service and prints the inputs and outputs.  It refuses to call
+// a real production service, or a real service at all in CI unless VMOCKHELPER_ALLOW_REAL is set or the package is
+// built with the vmockhelper_real tag, and instead works like MockCallsAndPrintExpected.  Options can limit which
+// methods are relayed to the real service, how often and how many times, and set timeouts and retries for the calls
+// that are
+func UseRealAndPrintExpected(gomockObject interface{}, realService interface{}, mockAlias string, opts ...RelayOption) {
+	if reason := realRelayBlocked(); reason != "" {
+		emitf("!!! vmockhelper: %s will not call the real service because %s. It will return zero values instead. Remove UseRealAndPrintExpected before pushing your code !!!", mockAlias, reason)
+		MockCallsAndPrintExpected(gomockObject, mockAlias)
+		return
+	}
+	relay := newRelayConfig(opts)
+	reporter := testReporter(gomockObject)
+	if err := relay.checkAdapters(gomockObject); err != nil {
+		reportError(reporter, fmt.Sprintf("vmockhelper: %s will not call the real service: %s", mockAlias, err.Error()))
+		MockCallsAndPrintExpected(gomockObject, mockAlias)
+		return
+	}
+	relay.budget.shareWithTest(reporter)
+	handleAllMethods(gomockObject, mockAlias, func(methodName string, methodType reflect.Type, args []reflect.Value) ([]reflect.Value, string) {
+		if !relay.relays(methodName) {
+			return mockCall(reporter, mockAlias, methodName, methodType, args, nil, "not relayed to the real service, returned mock values")
+		}
+		if !relay.budget.spend() {
+			return mockCall(reporter, mockAlias, methodName, methodType, args, nil, relay.budget.exhausted(reporter, mockAlias, methodName))
+		}
+		realArgs, returns, note := relay.callReal(realService, methodName, args)
+		printExpected(reporter, mockAlias, methodName, realArgs, returns, note)
+		return returns, sourceReal
+	})
+}
+
+// handleAllMethods creates an expected call for every method of a gomock mock that accepts any arguments any number of
+// times, and answers each call with handle, which also returns where its values came from.  If handle panics or
+// returns values of the wrong type, the test fails through the mock's controller and the call returns zero values.
+// Each call is recorded while recording, with copies of its arguments and return values taken when it is made, and
+// written to the event log if there is one
+func handleAllMethods(gomockObject interface{}, mockAlias string, handle func(methodName string, methodType reflect.Type, args []reflect.Value) ([]reflect.Value, string)) {
 	mock := reflect.ValueOf(gomockObject)
-	mockType := reflect.TypeOf(gomockObject)
+	reporter := testReporter(gomockObject)
 
 	mockRecorder := mock.MethodByName("EXPECT").Call([]reflect.Value{})[0]
-
-	var methodNames []string
-	for i := 0; i < mockRecorder.Type().NumMethod(); i++ {
-		methodNames = append(methodNames, mockRecorder.Type().Method(i).Name)
-	}
-	for _, methodName := range methodNames {
+	for _, methodName := range mockMethods(gomockObject) {
+		methodName := methodName
 		methodType := mock.MethodByName(methodName).Type()
-		method, _ := mockType.MethodByName(methodName)
 
 		var inputParameters []reflect.Value
 		numberOfInputParameters := mockRecorder.MethodByName(methodName).Type().NumIn()
@@ -63,92 +130,194 @@ func MockCallsAndPrintExpected(gomockObject interface{}, mockAlias string, mockR
 
 		mockCall := mockRecorder.MethodByName(methodName).Call(inputParameters)[0]
 		call := mockCall.Interface().(*gomock.Call)
-		doAndReturnFunction := reflect.MakeFunc(methodType, func(args []reflect.Value) []reflect.Value {
-			var returns []reflect.Value
-			for i := 0; i < methodType.NumOut(); i++ {
-				if len(mockResponseArgs) > i && mockResponseArgs[i] != nil {
-					returns = append(returns, reflect.ValueOf(mockResponseArgs[i]))
-				} else {
-					if !record {
-						returns = append(returns, reflect.Zero(methodType.Out(i)))
-					} else {
-						returns = append(returns, NewFilledType(methodType.Out(i)))
-					}
-				}
+		function := reflect.MakeFunc(methodType, func(args []reflect.Value) (returns []reflect.Value) {
+			defer recoverCall(reporter, mockAlias, methodName, methodType, args, &returns)
+			start := time.Now()
+			recording, testCase := reserveRecording(mockAlias, methodName)
+			var sent []reflect.Value
+			if recording {
+				sent = snapshot(args)
 			}
-			inputString := valuesToCodeString(args)
-			returnString := valuesToCodeString(returns)
-
-			if record {
-				recordedCalls = append(recordedCalls, Call{
-					method:  method.Name,
-					alias:   mockAlias,
-					args:    args,
-					returns: returns,
-				})
+			returns, source := handle(methodName, methodType, args)
+			returns = checkReturns(methodType, returns)
+			if recording {
+				call := Call{
+					method:   methodName,
+					alias:    mockAlias,
+					args:     redact(sent),
+					returns:  redact(snapshot(returns)),
+					test:     testName(reporter),
+					source:   source,
+					testCase: testCase,
+				}
+				recordingLock.Lock()
+				recordedCalls = append(recordedCalls, call)
+				recordingLock.Unlock()
 			}
-
-			logging.Alertf(context.Background(), fmt.Sprintf(mockFMT, mockAlias, method.Name, inputString, returnString))
+			logEvent(reporter, mockAlias, methodName, args, returns, source, time.Since(start))
 			return returns
 		})
-		call.DoAndReturn(doAndReturnFunction.Interface()).AnyTimes()
+		call.DoAndReturn(function.Interface()).AnyTimes()
 	}
 }
 
-// UseRealAndPrintExpected takes in a gomock object and an instance of the actual service being mocked.  When a mock
-// method is called, it calls the same method on the real service and prints the inputs and outputs
-func UseRealAndPrintExpected(gomockObject interface{}, realService interface{}, mockAlias string) {
-	mock := reflect.ValueOf(gomockObject)
-	mockType := reflect.TypeOf(gomockObject)
-
-	mockRecorder := mock.MethodByName("EXPECT").Call([]reflect.Value{})[0]
-
+// mockMethods returns the names of the methods of a gomock mock, which are the methods of its recorder
+func mockMethods(gomockObject interface{}) []string {
+	recorderType := reflect.ValueOf(gomockObject).MethodByName("EXPECT").Type().Out(0)
 	var methodNames []string
-	for i := 0; i < mockRecorder.Type().NumMethod(); i++ {
-		methodNames = append(methodNames, mockRecorder.Type().Method(i).Name)
+	for i := 0; i < recorderType.NumMethod(); i++ {
+		methodNames = append(methodNames, recorderType.Method(i).Name)
 	}
-	for _, methodName := range methodNames {
-		methodType := mock.MethodByName(methodName).Type()
-		method, _ := mockType.MethodByName(methodName)
+	return methodNames
+}
 
-		var inputParameters []reflect.Value
-		numberOfInputParameters := mockRecorder.MethodByName(methodName).Type().NumIn()
-		for i := 0; i < numberOfInputParameters; i++ {
-			inputParameters = append(inputParameters, reflect.ValueOf(gomock.Any()))
+// mockCall answers a call to a mock method without calling a real service.  It returns mockResponseArgs where they are
+// given, and zero values otherwise, or filled values while recording, along with where the values came from.  The call
+// is printed along with note, if there is one
+func mockCall(reporter gomock.TestReporter, mockAlias string, methodName string, methodType reflect.Type, args []reflect.Value, mockResponseArgs []interface{}, note string) ([]reflect.Value, string) {
+	var returns []reflect.Value
+	filled := recording()
+	source := sourceZero
+	if filled {
+		source = sourceFilled
+	}
+	for i := 0; i < methodType.NumOut(); i++ {
+		if len(mockResponseArgs) > i && mockResponseArgs[i] != nil {
+			returns = append(returns, reflect.ValueOf(mockResponseArgs[i]))
+			source = sourceMocked
+		} else {
+			if !filled {
+				returns = append(returns, reflect.Zero(methodType.Out(i)))
+			} else {
+				returns = append(returns, NewFilledType(methodType.Out(i)))
+			}
 		}
+	}
+	printExpected(reporter, mockAlias, methodName, args, returns, note)
+	return returns, source
+}
 
-		mockCall := mockRecorder.MethodByName(methodName).Call(inputParameters)[0]
-		call := mockCall.Interface().(*gomock.Call)
-		function := reflect.MakeFunc(methodType, func(args []reflect.Value) []reflect.Value {
-			v := reflect.ValueOf(realService)
-			if v.MethodByName(method.Name).Type().IsVariadic() {
-				lastArgument := args[len(args)-1]
-				args = args[:len(args)-1]
-				var variadicList []reflect.Value
-				for i := 0; i < lastArgument.Len(); i++ {
-					variadicList = append(variadicList, lastArgument.Index(i))
-				}
-				args = append(args, variadicList...)
-			}
-			returns := v.MethodByName(method.Name).Call(args)
-			inputString := valuesToCodeString(args)
-			returnString := valuesToCodeString(returns)
+// printExpected prints the expected call for a mock method that received args and returned returns.  The code that
+// called the mock and any note are printed as comments above the call.  When deduplicating, an expected call already
+// printed in the same test is only counted.  Nothing is rendered when the sink discards output, unless deduplicating
+// needs the expected call to count it
+func printExpected(reporter gomock.TestReporter, mockAlias string, methodName string, args []reflect.Value, returns []reflect.Value, note string) {
+	discard := discarding()
+	if discard && !dedupeExpected {
+		return
+	}
+	args = redact(args)
+	returns = redact(returns)
+	expectedImportsLock.Lock()
+	imports := importsFor(reporter)
+	r := &renderer{imports: imports.set}
+	namePrefix := mockAlias + methodName
+	inputDeclarations, inputString := hoistValues(r, args, namePrefix+"In")
+	returnDeclarations, returnString := hoistValues(r, returns, namePrefix+"Out")
+	expected := fmt.Sprintf(mockFMT, mockAlias, methodName, inputString, returnString)
+	if dedupeExpected {
+		if tallyExpected(reporter, inputDeclarations+returnDeclarations, strings.TrimSpace(expected)) > 1 || discard {
+			expectedImportsLock.Unlock()
+			return
+		}
+	}
+	if inputDeclarations+returnDeclarations != "" {
+		// The variables of every call after the first are numbered, so that the calls of a test can be pasted together
+		imports.hoisted[namePrefix]++
+		if number := imports.hoisted[namePrefix]; number > 1 {
+			namePrefix = fmt.Sprintf("%sCall%d", namePrefix, number)
+			inputDeclarations, inputString = hoistValues(r, args, namePrefix+"In")
+			returnDeclarations, returnString = hoistValues(r, returns, namePrefix+"Out")
+			expected = fmt.Sprintf(mockFMT, mockAlias, methodName, inputString, returnString)
+		}
+	}
+	if note != "" {
+		expected = fmt.Sprintf("\n// %s: %s%s", mockAlias+"."+methodName, note, expected)
+	}
+	if site := callSite(); site != "" {
+		expected = fmt.Sprintf("\n// %s called from %s%s", mockAlias+"."+methodName, site, expected)
+	}
+	if declarations := inputDeclarations + returnDeclarations; declarations != "" {
+		expected = "\n" + declarations + strings.TrimPrefix(expected, "\n")
+	}
+	// Only the imports that have not been printed yet are printed, so that each one is printed once per test
+	newImports := imports.set.specs(imports.printed)
+	if len(newImports) > 0 {
+		for path := range imports.set.aliases {
+			imports.printed[path] = true
+		}
+		expected = "\n" + importBlock(newImports) + expected
+	}
+	expectedImportsLock.Unlock()
+	emit(expected)
+}
 
-			logging.Alertf(context.Background(), fmt.Sprintf(mockFMT, mockAlias, method.Name, inputString, returnString))
-			return returns
+// importsFor returns the imports of the expectations printed by the test reported to by reporter, which are forgotten
+// when the test ends.  expectedImportsLock must be held
+func importsFor(reporter gomock.TestReporter) *testImports {
+	name := testName(reporter)
+	imports, ok := expectedImports[name]
+	if ok {
+		return imports
+	}
+	imports = &testImports{set: newImportSet(""), printed: map[string]bool{}, hoisted: map[string]int{}}
+	expectedImports[name] = imports
+	if cleanuper, ok := reporter.(interface{ Cleanup(func()) }); ok {
+		cleanuper.Cleanup(func() {
+			expectedImportsLock.Lock()
+			defer expectedImportsLock.Unlock()
+			delete(expectedImports, name)
 		})
-		call.DoAndReturn(function.Interface()).AnyTimes()
 	}
+	return imports
+}
+
+// hoistValues works like valuesToCodeString, but values longer than the hoist threshold are declared as variables
+// named with namePrefix and their position, skipping contexts.  It returns the declarations and the list of arguments
+func hoistValues(r *renderer, values []reflect.Value, namePrefix string) (string, string) {
+	if hoistThreshold <= 0 {
+		return "", valuesToCodeString(r, values)
+	}
+	var declarations string
+	var full []string
+	indexOffset := 1
+	for i, value := range values {
+		if isContext(value) {
+			indexOffset--
+			full = append(full, r.ident(gomockPath, "Any")+"()")
+			continue
+		}
+		code := r.code(value)
+		if len(code) <= hoistThreshold {
+			full = append(full, code)
+			continue
+		}
+		name := fmt.Sprintf("%s%d", namePrefix, i+indexOffset)
+		multiline := *r
+		multiline.multiline = true
+		declarations += formatCode(fmt.Sprintf("%s := %s", name, multiline.code(value))) + "\n"
+		full = append(full, name)
+	}
+	return declarations, strings.Join(full, ", ")
+}
+
+// formatCode runs code through gofmt, returning it unchanged if it can not be parsed
+func formatCode(code string) string {
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return code
+	}
+	return string(formatted)
 }
 
-func valuesToCodeString(values []reflect.Value) string {
+func valuesToCodeString(r *renderer, values []reflect.Value) string {
 	var full string
 	for _, value := range values {
 		if isContext(value) {
-			full += "gomock.Any(), "
+			full += r.ident(gomockPath, "Any") + "(), "
 			continue
 		}
-		full += vrender.Render(value.Interface()) + ", "
+		full += r.code(value) + ", "
 	}
 	full = strings.TrimSuffix(full, ", ")
 	return full
@@ -0,0 +1,56 @@
+package vmockhelper
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoistLargeValuesNumbersRepeatedCalls(t *testing.T) {
+	buffer := useBuffer(t)
+	HoistLargeValues(10)
+	defer HoistLargeValues(0)
+	mock := NewMockTestService(gomock.NewController(t))
+	MockCallsAndPrintExpected(mock, "svc")
+
+	_, _ = mock.Get(context.Background(), "a long identifier")
+	_, _ = mock.Get(context.Background(), "another long identifier")
+	_, _ = mock.Get(context.Background(), "1")
+	_, _ = mock.Get(context.Background(), "a third long identifier")
+
+	printed := buffer.String()
+	assert.Contains(t, printed, "svcGetIn1 := \"a long identifier\"\nsvc.EXPECT().Get(gomock.Any(), svcGetIn1).Return(nil, nil)")
+	assert.Contains(t, printed, "svcGetCall2In1 := \"another long identifier\"\nsvc.EXPECT().Get(gomock.Any(), svcGetCall2In1).Return(nil, nil)")
+	assert.Contains(t, printed, `svc.EXPECT().Get(gomock.Any(), "1").Return(nil, nil)`)
+	assert.Contains(t, printed, "svcGetCall3In1 := \"a third long identifier\"")
+
+	declared := map[string]bool{}
+	for _, match := range regexp.MustCompile(`(\w+) :=`).FindAllStringSubmatch(printed, -1) {
+		assert.False(t, declared[match[1]], "%s is declared twice", match[1])
+		declared[match[1]] = true
+	}
+}
+
+func TestHoistLargeValuesWithDedupe(t *testing.T) {
+	buffer := useBuffer(t)
+	HoistLargeValues(10)
+	defer HoistLargeValues(0)
+	DedupeExpected(true)
+	defer DedupeExpected(false)
+
+	t.Run("calls", func(t *testing.T) {
+		mock := NewMockTestService(gomock.NewController(t))
+		MockCallsAndPrintExpected(mock, "svc")
+		_, _ = mock.Get(context.Background(), "a long identifier")
+		_, _ = mock.Get(context.Background(), "another long identifier")
+		_, _ = mock.Get(context.Background(), "a long identifier")
+	})
+
+	printed := buffer.String()
+	assert.Contains(t, printed, "svcGetIn1 := \"a long identifier\"\nsvc.EXPECT().Get(gomock.Any(), svcGetIn1).Return(nil, nil)\n")
+	assert.Contains(t, printed, "svcGetCall2In1 := \"another long identifier\"\nsvc.EXPECT().Get(gomock.Any(), svcGetCall2In1).Return(nil, nil)\n")
+	assert.Contains(t, printed, "{\n\tsvcGetIn1 := \"a long identifier\"\n\tsvc.EXPECT().Get(gomock.Any(), svcGetIn1).Return(nil, nil).Times(2)\n}")
+}
@@ -0,0 +1,107 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/golang/mock/gomock"
+)
+
+// testReporter returns the TestReporter of the controller gomockObject was created with, usually the test's
+// *testing.T, or nil if it can not be found
+func testReporter(gomockObject interface{}) gomock.TestReporter {
+	ctrl, err := controller(gomockObject)
+	if err != nil || ctrl.T == nil {
+		return nil
+	}
+	return ctrl.T
+}
+
+// recoverCall recovers from a panic while answering a call to a mock method, and reports it as a test failure instead
+// of letting it take down the whole test binary.  When returns is not nil it is set to zero values, so the call returns
+// normally.  It must be deferred
+func recoverCall(reporter gomock.TestReporter, mockAlias string, methodName string, methodType reflect.Type, args []reflect.Value, returns *[]reflect.Value) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	var argTypes []string
+	for _, arg := range args {
+		if arg.IsValid() {
+			argTypes = append(argTypes, arg.Type().String())
+		} else {
+			argTypes = append(argTypes, "nil")
+		}
+	}
+	reportError(reporter, fmt.Sprintf("vmockhelper: %s.%s(%s) panicked: %v", mockAlias, methodName, strings.Join(argTypes, ", "), recovered))
+
+	if returns != nil {
+		*returns = nil
+		for i := 0; i < methodType.NumOut(); i++ {
+			*returns = append(*returns, reflect.Zero(methodType.Out(i)))
+		}
+	}
+}
+
+// checkReturns replaces missing return values with zero values, and panics with a description of the problem if a
+// value can not be returned from a method of type methodType.  reflect.MakeFunc would otherwise panic with a message
+// that does not say which mock or method returned it
+func checkReturns(methodType reflect.Type, returns []reflect.Value) []reflect.Value {
+	if len(returns) != methodType.NumOut() {
+		panic(fmt.Sprintf("returned %d values, but the method returns %d", len(returns), methodType.NumOut()))
+	}
+	for i, value := range returns {
+		if !value.IsValid() {
+			returns[i] = reflect.Zero(methodType.Out(i))
+			continue
+		}
+		if !value.Type().AssignableTo(methodType.Out(i)) {
+			panic(fmt.Sprintf("returned %s as result %d, which must be %s", value.Type(), i+1, methodType.Out(i)))
+		}
+	}
+	return returns
+}
+
+// reportError fails the test reported to by reporter with message, or sends message to the sink if it is not a test
+func reportError(reporter gomock.TestReporter, message string) {
+	if reporter != nil {
+		reporter.Errorf("%s", message)
+	} else {
+		emitf("%s", message)
+	}
+}
+
+// controller returns the gomock controller gomockObject was created with, from the ctrl field mockgen generates for
+// every mock
+func controller(gomockObject interface{}) (*gomock.Controller, error) {
+	mock := reflect.ValueOf(gomockObject)
+	if mock.Kind() != reflect.Ptr || mock.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a gomock mock", gomockObject)
+	}
+	ctrlField := exposeField(mock.Elem(), "ctrl")
+	if !ctrlField.IsValid() {
+		return nil, fmt.Errorf("%T has no gomock controller", gomockObject)
+	}
+	ctrl, ok := ctrlField.Interface().(*gomock.Controller)
+	if !ok || ctrl == nil {
+		return nil, fmt.Errorf("%T has no gomock controller", gomockObject)
+	}
+	return ctrl, nil
+}
+
+// setController replaces the gomock controller gomockObject was created with, which must have been found by controller
+func setController(gomockObject interface{}, ctrl *gomock.Controller) {
+	exposeField(reflect.ValueOf(gomockObject).Elem(), "ctrl").Set(reflect.ValueOf(ctrl))
+}
+
+// exposeField returns the named field of the addressable struct v, including unexported fields, or an invalid value if
+// there is no such field
+func exposeField(v reflect.Value, name string) reflect.Value {
+	field := v.FieldByName(name)
+	if !field.IsValid() || !field.CanAddr() {
+		return reflect.Value{}
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
@@ -0,0 +1,168 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+// RedactionRule selects values that are replaced with pseudonyms before they are printed or recorded
+type RedactionRule struct {
+	fields  []*regexp.Regexp
+	types   []reflect.Type
+	pattern *regexp.Regexp
+}
+
+// RedactFields redacts the values of struct fields whose names match one of patterns.  A pattern is either a field
+// name, or a regular expression like `(?i)(email|phone)`
+func RedactFields(patterns ...string) RedactionRule {
+	return RedactionRule{fields: compileNamePatterns(patterns)}
+}
+
+// RedactTypes redacts every value with the same type as one of samples, for example RedactTypes(&oauth2.Token{})
+func RedactTypes(samples ...interface{}) RedactionRule {
+	var types []reflect.Type
+	for _, sample := range samples {
+		types = append(types, reflect.TypeOf(sample))
+	}
+	return RedactionRule{types: types}
+}
+
+// RedactPattern redacts every part of a string that matches the regular expression pattern
+func RedactPattern(pattern string) RedactionRule {
+	return RedactionRule{pattern: regexp.MustCompile(pattern)}
+}
+
+var redactionRules []RedactionRule
+var redactionRulesLock sync.RWMutex
+
+// pseudonyms maps each redacted value to the pseudonym it is replaced with, so the same value is always replaced with
+// the same pseudonym until Clear is called
+var pseudonyms = map[string]int{}
+var pseudonymsLock sync.Mutex
+
+// Redact sets the rules used to redact secrets and personal information from printed and recorded calls, replacing any
+// rules set before.  Each redacted value is replaced with a pseudonym, and the same value is always replaced with the
+// same pseudonym.  Strings are replaced with strings like "redacted-1", numbers with numbers, and other values with
+// their zero value.  The values returned to the code under test are not changed
+func Redact(rules ...RedactionRule) {
+	redactionRulesLock.Lock()
+	defer redactionRulesLock.Unlock()
+	redactionRules = rules
+}
+
+// redact returns copies of values with every value selected by the redaction rules replaced.  values is returned as
+// it is when there are no rules
+func redact(values []reflect.Value) []reflect.Value {
+	redactionRulesLock.RLock()
+	rules := redactionRules
+	redactionRulesLock.RUnlock()
+	if len(rules) == 0 {
+		return values
+	}
+	c := &copier{copies: map[pointerKey]reflect.Value{}, rules: rules}
+	var redacted []reflect.Value
+	for _, value := range values {
+		redacted = append(redacted, c.copy(value))
+	}
+	return redacted
+}
+
+// redactString replaces every part of a string matched by a RedactPattern rule with its pseudonym
+func (c *copier) redactString(v reflect.Value) reflect.Value {
+	redacted := v.String()
+	for _, rule := range c.rules {
+		if rule.pattern != nil {
+			redacted = rule.pattern.ReplaceAllStringFunc(redacted, pseudonym)
+		}
+	}
+	return reflect.ValueOf(redacted).Convert(v.Type())
+}
+
+// replace returns the pseudonym of a value selected by a redaction rule.  The elements of pointers, interfaces, slices,
+// arrays and maps are replaced one by one, and values that can't have a pseudonym are replaced with their zero value
+func (c *copier) replace(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(pseudonym(v.String())).Convert(v.Type())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(pseudonymIndex(fmt.Sprintf("int %d", v.Int()))).Convert(v.Type())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(pseudonymIndex(fmt.Sprintf("uint %d", v.Uint()))).Convert(v.Type())
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(pseudonymIndex(fmt.Sprintf("float %v", v.Float()))).Convert(v.Type())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(c.replace(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(c.replace(v.Elem()))
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(c.replace(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			copied.SetMapIndex(c.replace(key), c.replace(v.MapIndex(key)))
+		}
+		return copied
+	case reflect.Bool:
+		return v
+	}
+	return reflect.Zero(v.Type())
+}
+
+// pseudonym returns the pseudonym of a string, choosing the next one if the string has not been redacted before
+func pseudonym(value string) string {
+	return fmt.Sprintf("redacted-%d", pseudonymIndex("string "+value))
+}
+
+// pseudonymIndex returns the number of the pseudonym for a value, described by key
+func pseudonymIndex(key string) int {
+	pseudonymsLock.Lock()
+	defer pseudonymsLock.Unlock()
+	if index, ok := pseudonyms[key]; ok {
+		return index
+	}
+	index := len(pseudonyms) + 1
+	pseudonyms[key] = index
+	return index
+}
+
+func (c *copier) redactsType(t reflect.Type) bool {
+	for _, rule := range c.rules {
+		for _, redacted := range rule.types {
+			if t == redacted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *copier) redactsField(name string) bool {
+	for _, rule := range c.rules {
+		if matchesAny(rule.fields, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package vmockhelper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type redactEmail string
+
+type redactPerson struct {
+	Name    string
+	Email   redactEmail
+	Age     int
+	Friends map[string]int
+	Manager *redactPerson
+}
+
+// redactOne redacts a single value with the rules set by Redact
+func redactOne(value interface{}) interface{} {
+	return redact([]reflect.Value{reflect.ValueOf(value)})[0].Interface()
+}
+
+func TestRedactFields(t *testing.T) {
+	Clear()
+	Redact(RedactFields("Name", `(?i)^age$`))
+	defer Redact()
+
+	person := &redactPerson{Name: "Alice", Email: "alice@example.com", Age: 41, Manager: &redactPerson{Name: "Bob", Age: 41}}
+	redacted := redactOne(person).(*redactPerson)
+
+	assert.Equal(t, "redacted-1", redacted.Name)
+	assert.Equal(t, redactEmail("alice@example.com"), redacted.Email)
+	assert.Equal(t, 2, redacted.Age)
+	assert.Equal(t, "redacted-3", redacted.Manager.Name)
+	assert.Equal(t, 2, redacted.Manager.Age, "the same number is replaced with the same pseudonym")
+	assert.Equal(t, "Alice", person.Name, "the original value is not changed")
+}
+
+func TestRedactTypes(t *testing.T) {
+	Clear()
+	Redact(RedactTypes(redactEmail("")))
+	defer Redact()
+
+	alice := redactOne(redactPerson{Name: "Alice", Email: "alice@example.com"}).(redactPerson)
+	bob := redactOne(redactPerson{Name: "Bob", Email: "bob@example.com"}).(redactPerson)
+	aliceAgain := redactOne(redactPerson{Name: "Alice", Email: "alice@example.com"}).(redactPerson)
+
+	assert.Equal(t, redactEmail("redacted-1"), alice.Email)
+	assert.Equal(t, redactEmail("redacted-2"), bob.Email)
+	assert.Equal(t, alice.Email, aliceAgain.Email)
+	assert.Equal(t, "Alice", alice.Name)
+}
+
+func TestRedactPattern(t *testing.T) {
+	Clear()
+	Redact(RedactPattern(`[\w.]+@[\w.]+`))
+	defer Redact()
+
+	assert.Equal(t, "write to redacted-1 or redacted-2", redactOne("write to alice@example.com or bob@example.com"))
+	assert.Equal(t, redactEmail("redacted-1"), redactOne(redactEmail("alice@example.com")))
+}
+
+func TestRedactMapKeys(t *testing.T) {
+	Clear()
+	Redact(RedactPattern(`[\w.]+@[\w.]+`))
+	defer Redact()
+
+	person := redactPerson{Friends: map[string]int{"alice@example.com": 1, "bob@example.com": 2}}
+	redacted := redactOne(person).(redactPerson)
+
+	assert.Len(t, redacted.Friends, 2)
+	for key := range redacted.Friends {
+		assert.Regexp(t, `^redacted-\d$`, key)
+	}
+	assert.Contains(t, person.Friends, "alice@example.com")
+}
+
+func TestPseudonymsLastUntilClear(t *testing.T) {
+	Clear()
+	Redact(RedactFields("Name"))
+	defer Redact()
+
+	assert.Equal(t, "redacted-1", redactOne(redactPerson{Name: "Alice"}).(redactPerson).Name)
+	assert.Equal(t, "redacted-2", redactOne(redactPerson{Name: "Bob"}).(redactPerson).Name)
+	assert.Equal(t, "redacted-1", redactOne(redactPerson{Name: "Alice"}).(redactPerson).Name)
+
+	Clear()
+	assert.Equal(t, "redacted-1", redactOne(redactPerson{Name: "Bob"}).(redactPerson).Name)
+}
+
+func TestRedactWithoutRules(t *testing.T) {
+	Redact()
+	person := &redactPerson{Name: "Alice"}
+	assert.Same(t, person, redactOne(person))
+}
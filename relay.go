@@ -0,0 +1,327 @@
+package vmockhelper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readOnlyPattern matches the names of methods that read data without changing it
+const readOnlyPattern = `^(Get|List|Search|Lookup|Find|Read|Fetch|Count|Exists)`
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// defaultRetryCodes are the gRPC codes retried when Retry is not given any
+var defaultRetryCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded}
+
+// retryBackoff is how long to wait before the first retry.  Each retry after that waits one retryBackoff longer
+const retryBackoff = 100 * time.Millisecond
+
+// RelayOption configures how UseRealAndPrintExpected relays calls to the real service
+type RelayOption func(*relayConfig)
+
+type relayConfig struct {
+	allow      []*regexp.Regexp
+	deny       []*regexp.Regexp
+	timeouts   []methodTimeout
+	retries    int
+	retryCodes []codes.Code
+	renames    map[string]string
+	adapters   map[string]reflect.Value
+	budget     relayBudget
+}
+
+type methodTimeout struct {
+	patterns []*regexp.Regexp
+	timeout  time.Duration
+}
+
+func newRelayConfig(opts []RelayOption) *relayConfig {
+	c := &relayConfig{renames: map[string]string{}, adapters: map[string]reflect.Value{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.budget.share(&budgetUsage{})
+	return c
+}
+
+// AllowMethods only relays the methods matching one of patterns to the real service.  Every other method returns mock
+// values like MockCallsAndPrintExpected.  A pattern is either a method name, or a regular expression like
+// `^(Get|List|Search)`
+func AllowMethods(patterns ...string) RelayOption {
+	return func(c *relayConfig) {
+		c.allow = append(c.allow, compileNamePatterns(patterns)...)
+	}
+}
+
+// DenyMethods never relays the methods matching one of patterns to the real service, and returns mock values like
+// MockCallsAndPrintExpected instead.  A pattern is either a method name, or a regular expression like
+// `^(Create|Update|Delete)`
+func DenyMethods(patterns ...string) RelayOption {
+	return func(c *relayConfig) {
+		c.deny = append(c.deny, compileNamePatterns(patterns)...)
+	}
+}
+
+// ReadOnly only relays methods that read data to the real service, like Get, List and Search, so that recording a test
+// can not change the data of a shared environment
+func ReadOnly() RelayOption {
+	return AllowMethods(readOnlyPattern)
+}
+
+// Timeout limits how long a call to the real service can take.  It applies to the methods matching one of patterns, or
+// every method when there are none, with later timeouts taking precedence.  The context passed to the real service is
+// given the timeout, and if the service does not return in time the call returns a DeadlineExceeded error
+func Timeout(timeout time.Duration, patterns ...string) RelayOption {
+	return func(c *relayConfig) {
+		c.timeouts = append(c.timeouts, methodTimeout{patterns: compileNamePatterns(patterns), timeout: timeout})
+	}
+}
+
+// Retry calls the real service again, up to retries more times, when it returns an error with one of retryCodes.  With
+// no codes, Unavailable, ResourceExhausted, Aborted and DeadlineExceeded errors are retried
+func Retry(retries int, retryCodes ...codes.Code) RelayOption {
+	return func(c *relayConfig) {
+		c.retries = retries
+		c.retryCodes = retryCodes
+		if len(retryCodes) == 0 {
+			c.retryCodes = defaultRetryCodes
+		}
+	}
+}
+
+// RenameMethod relays calls to the mock method mockMethod to the method of the real service named realMethod, for mocks
+// of wrapper interfaces whose method names differ from the client they wrap.  The real method must take the same
+// arguments and return the same values as the mock method
+func RenameMethod(mockMethod string, realMethod string) RelayOption {
+	return func(c *relayConfig) {
+		c.renames[mockMethod] = realMethod
+	}
+}
+
+// AdaptMethod calls adapter instead of the real service when the mock method mockMethod is called.  adapter must be a
+// function with the same signature as the mock method, which converts the arguments, calls the real client, and
+// converts what it returns.  The test fails as soon as the mock is set up if it does not
+func AdaptMethod(mockMethod string, adapter interface{}) RelayOption {
+	return func(c *relayConfig) {
+		c.adapters[mockMethod] = reflect.ValueOf(adapter)
+	}
+}
+
+// method returns the function called on the real service for a mock method, which is its adapter, the method it is
+// renamed to, or the method of the real service with the same name.  It returns an invalid value if there is none
+func (c *relayConfig) method(realService interface{}, methodName string) reflect.Value {
+	if adapter, ok := c.adapters[methodName]; ok {
+		return adapter
+	}
+	return reflect.ValueOf(realService).MethodByName(c.realName(methodName))
+}
+
+// checkAdapters returns an error if an adapter is not a function, or, when gomockObject is not nil, if the mock has no
+// method with the adapter's name and signature
+func (c *relayConfig) checkAdapters(gomockObject interface{}) error {
+	for methodName, adapter := range c.adapters {
+		if adapter.Kind() != reflect.Func {
+			return fmt.Errorf("the adapter for %s is %s, not a function", methodName, describeValue(adapter))
+		}
+		if gomockObject == nil {
+			continue
+		}
+		method := reflect.ValueOf(gomockObject).MethodByName(methodName)
+		if !method.IsValid() {
+			return fmt.Errorf("there is an adapter for %s, but the mock has no method %s", methodName, methodName)
+		}
+		if adapter.Type() != method.Type() {
+			return fmt.Errorf("the adapter for %s is a %s, but the mock method is a %s", methodName, adapter.Type(), method.Type())
+		}
+	}
+	return nil
+}
+
+// describeValue returns the type of v, or nil if v is not valid
+func describeValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return "a " + v.Type().String()
+}
+
+// realName returns the name of the method of the real service called for a mock method
+func (c *relayConfig) realName(methodName string) string {
+	if realMethod, ok := c.renames[methodName]; ok {
+		return realMethod
+	}
+	return methodName
+}
+
+// relays returns whether a method should be relayed to the real service
+func (c *relayConfig) relays(methodName string) bool {
+	if matchesAny(c.deny, methodName) {
+		return false
+	}
+	return len(c.allow) == 0 || matchesAny(c.allow, methodName)
+}
+
+// compileNamePatterns compiles method or field name patterns.  Names only match themselves, and anything else is a
+// regular expression
+func compileNamePatterns(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if identifierPattern.MatchString(pattern) {
+			pattern = "^" + regexp.QuoteMeta(pattern) + "$"
+		}
+		compiled = append(compiled, regexp.MustCompile(pattern))
+	}
+	return compiled
+}
+
+func matchesAny(patterns []*regexp.Regexp, methodName string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(methodName) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeoutFor returns the timeout of a method, or 0 if it has none
+func (c *relayConfig) timeoutFor(methodName string) time.Duration {
+	var timeout time.Duration
+	for _, t := range c.timeouts {
+		if len(t.patterns) == 0 || matchesAny(t.patterns, methodName) {
+			timeout = t.timeout
+		}
+	}
+	return timeout
+}
+
+// retryable returns whether an error with code should be retried
+func (c *relayConfig) retryable(code codes.Code) bool {
+	for _, retryCode := range c.retryCodes {
+		if code == retryCode {
+			return true
+		}
+	}
+	return false
+}
+
+// callReal calls the method of the real service for a mock method, with the timeout and retries configured for it.
+// The last argument of a variadic method is spread into the arguments the real service is called with.  It returns
+// those arguments, what the real service returned, and a note describing any timeouts and retries
+func (c *relayConfig) callReal(realService interface{}, methodName string, args []reflect.Value) ([]reflect.Value, []reflect.Value, string) {
+	method := c.method(realService, methodName)
+	if !method.IsValid() {
+		panic(fmt.Sprintf("the real service %T has no method %s", realService, c.realName(methodName)))
+	}
+	if method.Type().IsVariadic() {
+		lastArgument := args[len(args)-1]
+		args = append([]reflect.Value{}, args[:len(args)-1]...)
+		var variadicList []reflect.Value
+		for i := 0; i < lastArgument.Len(); i++ {
+			variadicList = append(variadicList, lastArgument.Index(i))
+		}
+		args = append(args, variadicList...)
+	}
+	returns, note := c.call(method, methodName, args)
+	return args, returns, note
+}
+
+// call calls a method of the real service with the timeout and retries configured for it.  The first attempt must
+// already have been taken from the budget, and every retry is taken from it too.  It returns what the method returned,
+// and a note describing any timeouts and retries
+func (c *relayConfig) call(method reflect.Value, methodName string, args []reflect.Value) ([]reflect.Value, string) {
+	timeout := c.timeoutFor(methodName)
+	var notes []string
+	for attempt := 1; ; attempt++ {
+		c.budget.wait()
+		returns, timedOut := callWithTimeout(method, args, timeout)
+		if timedOut {
+			notes = append(notes, fmt.Sprintf("attempt %d timed out after %s", attempt, timeout))
+		}
+		code, failed := returnedCode(returns)
+		if !failed || attempt > c.retries || !c.retryable(code) {
+			return returns, strings.Join(notes, ", ")
+		}
+		if !timedOut {
+			notes = append(notes, fmt.Sprintf("attempt %d failed with %s", attempt, code))
+		}
+		if !c.budget.spend() {
+			maxCalls, _ := c.budget.limit()
+			notes = append(notes, fmt.Sprintf("not retried because the budget of %d real calls is spent", maxCalls))
+			return returns, strings.Join(notes, ", ")
+		}
+		time.Sleep(time.Duration(attempt) * retryBackoff)
+	}
+}
+
+// callWithTimeout calls method, giving up once timeout has passed.  Any context passed to the method is given the
+// timeout and cancelled once the call is done.  A timeout of 0 waits for the method to return
+func callWithTimeout(method reflect.Value, args []reflect.Value, timeout time.Duration) ([]reflect.Value, bool) {
+	if timeout <= 0 {
+		return method.Call(args), false
+	}
+	var contexts []context.Context
+	callArgs := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		callArgs[i] = arg
+		if !isContext(arg) {
+			continue
+		}
+		ctx := context.Background()
+		if parent, ok := arg.Interface().(context.Context); ok && parent != nil {
+			ctx = parent
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		contexts = append(contexts, ctx)
+		callArgs[i] = reflect.ValueOf(ctx)
+	}
+
+	done := make(chan []reflect.Value, 1)
+	go func() {
+		done <- method.Call(callArgs)
+	}()
+	select {
+	case returns := <-done:
+		for _, ctx := range contexts {
+			if ctx.Err() == context.DeadlineExceeded {
+				return returns, true
+			}
+		}
+		return returns, false
+	case <-time.After(timeout):
+		return timedOutReturns(method.Type(), timeout), true
+	}
+}
+
+// timedOutReturns returns zero values for a method that did not return in time, with a DeadlineExceeded error for
+// every error it returns
+func timedOutReturns(methodType reflect.Type, timeout time.Duration) []reflect.Value {
+	var returns []reflect.Value
+	for i := 0; i < methodType.NumOut(); i++ {
+		value := reflect.New(methodType.Out(i)).Elem()
+		if methodType.Out(i) == errorInterface {
+			value.Set(reflect.ValueOf(status.Errorf(codes.DeadlineExceeded, "vmockhelper: timed out after %s", timeout)))
+		}
+		returns = append(returns, value)
+	}
+	return returns
+}
+
+// returnedCode returns the gRPC code of the error a method returned as its last value, if it returned one
+func returnedCode(returns []reflect.Value) (codes.Code, bool) {
+	if len(returns) == 0 {
+		return codes.OK, false
+	}
+	err, ok := asError(returns[len(returns)-1])
+	if !ok {
+		return codes.OK, false
+	}
+	return status.Code(err), true
+}
@@ -0,0 +1,122 @@
+package vmockhelper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRelaysMatchingMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []RelayOption
+		relayed []string
+		mocked  []string
+	}{
+		{name: "everything", relayed: []string{"Get", "Create", "List"}},
+		{name: "allowed names", opts: []RelayOption{AllowMethods("Get", "List")}, relayed: []string{"Get", "List"}, mocked: []string{"Create", "GetAll"}},
+		{name: "allowed pattern", opts: []RelayOption{AllowMethods("^(Get|List)")}, relayed: []string{"Get", "GetAll", "List"}, mocked: []string{"Create"}},
+		{name: "denied pattern", opts: []RelayOption{DenyMethods("^(Create|Update|Delete)")}, relayed: []string{"Get", "List"}, mocked: []string{"Create", "DeleteAll"}},
+		{name: "denied wins", opts: []RelayOption{AllowMethods("^Get"), DenyMethods("GetSecret")}, relayed: []string{"Get"}, mocked: []string{"GetSecret", "List"}},
+		{name: "read only", opts: []RelayOption{ReadOnly()}, relayed: []string{"Get", "List", "Search", "Exists"}, mocked: []string{"Create", "Update", "Delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := newRelayConfig(tt.opts)
+			for _, method := range tt.relayed {
+				assert.True(t, relay.relays(method), method)
+			}
+			for _, method := range tt.mocked {
+				assert.False(t, relay.relays(method), method)
+			}
+		})
+	}
+}
+
+func TestUseRealAndPrintExpectedOnlyRelaysAllowedMethods(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	real := &fakeService{name: "Alice"}
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, real, "svc", ReadOnly())
+
+	thing, _ := mock.Get(context.Background(), "1")
+	id, err := mock.Create(context.Background(), &testThing{Name: "new"})
+
+	assert.Equal(t, "Alice", thing.Name)
+	assert.Empty(t, id)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Get"}, real.called())
+	assert.Contains(t, buffer.String(), "not relayed to the real service, returned mock values")
+}
+
+func TestTimeoutFor(t *testing.T) {
+	relay := newRelayConfig([]RelayOption{Timeout(time.Second), Timeout(time.Minute, "^List"), Timeout(time.Millisecond, "ListAll")})
+
+	assert.Equal(t, time.Second, relay.timeoutFor("Get"))
+	assert.Equal(t, time.Minute, relay.timeoutFor("List"))
+	assert.Equal(t, time.Millisecond, relay.timeoutFor("ListAll"), "later timeouts take precedence")
+	assert.Zero(t, newRelayConfig(nil).timeoutFor("Get"))
+}
+
+func TestUseRealAndPrintExpectedTimesOut(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, &fakeService{name: "Alice", delay: time.Minute}, "svc", Timeout(20*time.Millisecond))
+
+	start := time.Now()
+	thing, err := mock.Get(context.Background(), "1")
+
+	assert.Less(t, time.Since(start), time.Minute/2)
+	assert.Nil(t, thing)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Contains(t, buffer.String(), "attempt 1 timed out after 20ms")
+}
+
+func TestUseRealAndPrintExpectedRetries(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	real := &fakeService{name: "Alice", failures: 2}
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, real, "svc", Retry(2))
+
+	thing, err := mock.Get(context.Background(), "1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", thing.Name)
+	assert.Len(t, real.called(), 3)
+	assert.Contains(t, buffer.String(), "attempt 1 failed with Unavailable, attempt 2 failed with Unavailable")
+}
+
+func TestUseRealAndPrintExpectedOnlyRetriesRetryCodes(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	useBuffer(t)
+	real := &fakeService{name: "Alice", failures: 2}
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, real, "svc", Retry(2, codes.NotFound))
+
+	_, err := mock.Get(context.Background(), "1")
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Len(t, real.called(), 1)
+}
+
+func TestRetriesCountAgainstTheBudget(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	real := &fakeService{name: "Alice", failures: 2}
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, real, "svc", Retry(2), CallBudget(2, false))
+
+	_, err := mock.Get(context.Background(), "1")
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Len(t, real.called(), 2)
+	assert.Contains(t, buffer.String(), "not retried because the budget of 2 real calls is spent")
+}
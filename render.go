@@ -0,0 +1,561 @@
+package vmockhelper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vendasta/gosdks/verrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Paths of the packages referenced by rendered code
+const (
+	timePath        = "time"
+	errorsPath      = "errors"
+	fmtPath         = "fmt"
+	codesPath       = "google.golang.org/grpc/codes"
+	statusPath      = "google.golang.org/grpc/status"
+	verrorsPath     = "github.com/vendasta/gosdks/verrors"
+	timestamppbPath = "google.golang.org/protobuf/types/known/timestamppb"
+	durationpbPath  = "google.golang.org/protobuf/types/known/durationpb"
+	wrapperspbPath  = "google.golang.org/protobuf/types/known/wrapperspb"
+	gomockPath      = "github.com/golang/mock/gomock"
+)
+
+var errorInterface = reflect.TypeOf((*error)(nil)).Elem()
+var errorsNewType = reflect.TypeOf(errors.New(""))
+var enumInterface = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+var stringType = reflect.TypeOf("")
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// wrapperConstructors maps each protobuf wrapper type to the wrapperspb function that constructs it
+var wrapperConstructors = map[reflect.Type]string{
+	reflect.TypeOf(wrapperspb.DoubleValue{}): "Double",
+	reflect.TypeOf(wrapperspb.FloatValue{}):  "Float",
+	reflect.TypeOf(wrapperspb.Int64Value{}):  "Int64",
+	reflect.TypeOf(wrapperspb.UInt64Value{}): "UInt64",
+	reflect.TypeOf(wrapperspb.Int32Value{}):  "Int32",
+	reflect.TypeOf(wrapperspb.UInt32Value{}): "UInt32",
+	reflect.TypeOf(wrapperspb.BoolValue{}):   "Bool",
+	reflect.TypeOf(wrapperspb.StringValue{}): "String",
+	reflect.TypeOf(wrapperspb.BytesValue{}):  "Bytes",
+}
+
+// durationUnits are the units a duration can be rendered in, from largest to smallest
+var durationUnits = []struct {
+	unit time.Duration
+	name string
+}{
+	{time.Hour, "Hour"},
+	{time.Minute, "Minute"},
+	{time.Second, "Second"},
+	{time.Millisecond, "Millisecond"},
+	{time.Microsecond, "Microsecond"},
+	{time.Nanosecond, "Nanosecond"},
+}
+
+var registeredConstants = map[interface{}]string{}
+var registeredConstantsLock sync.RWMutex
+
+// RegisterConstant registers the name of a constant so that it is printed by name instead of by value.  Protobuf enums
+// are resolved automatically, this is only needed for other enum-like types, for example
+// RegisterConstant(verrors.NotFound, "verrors.NotFound").  Only booleans, numbers and strings can be constants, and an
+// error is returned for any other value
+func RegisterConstant(value interface{}, name string) error {
+	if value == nil || !isBasic(reflect.TypeOf(value).Kind()) {
+		return fmt.Errorf("vmockhelper: %T can not be registered as a constant, only booleans, numbers and strings can", value)
+	}
+	registeredConstantsLock.Lock()
+	defer registeredConstantsLock.Unlock()
+	registeredConstants[value] = name
+	return nil
+}
+
+// renderValue converts a value to a string of Go code
+func renderValue(v reflect.Value) string {
+	return (&renderer{}).code(v)
+}
+
+// renderer converts values to Go code.  It follows the same output format as vrender, but renders errors, enums and
+// well known types as the code a person would write for them
+type renderer struct {
+	// visiting holds the pointers currently being rendered, to avoid infinite recursion
+	visiting map[uintptr]bool
+	// multiline puts each element of a composite literal on its own line
+	multiline bool
+	// variables maps strings to the name of the variable that should be printed in their place
+	variables map[string]string
+	// imports collects the packages referenced by the rendered code.  When nil, identifiers are qualified with the name
+	// of their package
+	imports *importSet
+}
+
+// code converts a value to a string of Go code
+func (r *renderer) code(v reflect.Value) string {
+	r.visiting = map[uintptr]bool{}
+	buf := &bytes.Buffer{}
+	r.render(buf, 0, v, false)
+	return buf.String()
+}
+
+func (r *renderer) render(buf *bytes.Buffer, ptrs int, v reflect.Value, implicit bool) {
+	if !v.IsValid() {
+		buf.WriteString("nil")
+		return
+	}
+	if err, ok := asError(v); ok {
+		buf.WriteString(r.renderError(err))
+		return
+	}
+	if name, ok := r.constantName(v); ok && ptrs == 0 {
+		buf.WriteString(name)
+		return
+	}
+
+	vt := v.Type()
+	pe := uintptr(0)
+	switch vt.Kind() {
+	case reflect.Ptr:
+		switch v.Elem().Kind() {
+		case reflect.Struct, reflect.Array:
+			pe = v.Pointer()
+		}
+	case reflect.Slice, reflect.Map:
+		pe = v.Pointer()
+	}
+	if pe != 0 {
+		if r.visiting[pe] {
+			buf.WriteString("<REC(")
+			if !implicit {
+				r.writeType(buf, ptrs, vt)
+			}
+			buf.WriteString(")>")
+			return
+		}
+		r.visiting[pe] = true
+		defer delete(r.visiting, pe)
+	}
+
+	switch vt.Kind() {
+	case reflect.Struct:
+		if vt == timeType && v.CanInterface() {
+			buf.WriteString(r.renderTime(v.Interface().(time.Time)))
+			return
+		}
+		if !implicit {
+			r.writeType(buf, ptrs, vt)
+		}
+		structAnon := vt.Name() == ""
+		buf.WriteRune('{')
+		written := 0
+		for i := 0; i < vt.NumField(); i++ {
+			if !v.Field(i).CanInterface() {
+				continue
+			}
+			r.writeSeparator(buf, written)
+			written++
+			anon := structAnon && isAnon(vt.Field(i).Type)
+			if !anon {
+				buf.WriteString(vt.Field(i).Name)
+				buf.WriteRune(':')
+			}
+			r.render(buf, 0, v.Field(i), anon)
+		}
+		r.writeClose(buf, written)
+
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("nil")
+			return
+		}
+		fallthrough
+
+	case reflect.Array:
+		if vt.Elem().Kind() == reflect.Uint8 && v.CanInterface() && vt.Kind() == reflect.Slice {
+			buf.WriteString(renderBytes(v.Bytes()))
+			return
+		}
+		if !implicit {
+			r.writeType(buf, ptrs, vt)
+		}
+		anon := vt.Name() == "" && isAnon(vt.Elem())
+		buf.WriteRune('{')
+		for i := 0; i < v.Len(); i++ {
+			r.writeSeparator(buf, i)
+			r.render(buf, 0, v.Index(i), anon)
+		}
+		r.writeClose(buf, v.Len())
+
+	case reflect.Map:
+		if !implicit {
+			r.writeType(buf, ptrs, vt)
+		}
+		if v.IsNil() {
+			buf.WriteString("(nil)")
+			return
+		}
+		buf.WriteRune('{')
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessValue(keys[i], keys[j])
+		})
+		keyAnon := isAnonKey(vt.Key())
+		valAnon := vt.Name() == "" && isAnon(vt.Elem())
+		for i, key := range keys {
+			r.writeSeparator(buf, i)
+			r.render(buf, 0, key, keyAnon)
+			buf.WriteRune(':')
+			r.render(buf, 0, v.MapIndex(key), valAnon)
+		}
+		r.writeClose(buf, len(keys))
+
+	case reflect.Ptr:
+		if !v.IsNil() && ptrs == 0 {
+			if code, ok := r.renderWellKnown(v); ok {
+				buf.WriteString(code)
+				return
+			}
+		}
+		ptrs++
+		fallthrough
+
+	case reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("nil")
+			return
+		}
+		r.render(buf, ptrs, v.Elem(), false)
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		r.writeType(buf, ptrs, vt)
+		fmt.Fprintf(buf, "(0x%016x)", v.Pointer())
+
+	default:
+		if vt == stringType && ptrs == 0 {
+			if name, ok := r.variables[v.String()]; ok {
+				buf.WriteString(name)
+				return
+			}
+		}
+		if vt == durationType && ptrs == 0 {
+			buf.WriteString(r.renderDuration(time.Duration(v.Int())))
+			return
+		}
+		implicit = implicit || (ptrs == 0 && vt.Name() == vt.Kind().String() && vt.PkgPath() == "")
+		if !implicit {
+			r.writeType(buf, ptrs, vt)
+			buf.WriteRune('(')
+		}
+		switch vt.Kind() {
+		case reflect.String:
+			fmt.Fprintf(buf, "%q", v.String())
+		case reflect.Bool:
+			fmt.Fprintf(buf, "%v", v.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fmt.Fprintf(buf, "%d", v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			fmt.Fprintf(buf, "%d", v.Uint())
+		case reflect.Float32, reflect.Float64:
+			fmt.Fprintf(buf, "%g", v.Float())
+		case reflect.Complex64, reflect.Complex128:
+			fmt.Fprintf(buf, "%g", v.Complex())
+		}
+		if !implicit {
+			buf.WriteRune(')')
+		}
+	}
+}
+
+// renderWellKnown renders a pointer to a protobuf well known type as a call to its constructor
+func (r *renderer) renderWellKnown(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	switch value := v.Interface().(type) {
+	case *timestamppb.Timestamp:
+		return fmt.Sprintf("%s(%s)", r.ident(timestamppbPath, "New"), r.renderTime(value.AsTime())), true
+	case *durationpb.Duration:
+		return fmt.Sprintf("%s(%s)", r.ident(durationpbPath, "New"), r.renderDuration(value.AsDuration())), true
+	}
+	constructor, ok := wrapperConstructors[v.Type().Elem()]
+	if !ok {
+		return "", false
+	}
+	buf := &bytes.Buffer{}
+	r.render(buf, 0, v.Elem().FieldByName("Value"), true)
+	return fmt.Sprintf("%s(%s)", r.ident(wrapperspbPath, constructor), buf.String()), true
+}
+
+// writeSeparator writes what comes before the element at index i of a composite literal
+func (r *renderer) writeSeparator(buf *bytes.Buffer, i int) {
+	if i > 0 {
+		buf.WriteRune(',')
+		if !r.multiline {
+			buf.WriteRune(' ')
+		}
+	}
+	if r.multiline {
+		buf.WriteRune('\n')
+	}
+}
+
+// writeClose ends a composite literal with n elements
+func (r *renderer) writeClose(buf *bytes.Buffer, n int) {
+	if r.multiline && n > 0 {
+		buf.WriteString(",\n")
+	}
+	buf.WriteRune('}')
+}
+
+func (r *renderer) writeType(buf *bytes.Buffer, ptrs int, t reflect.Type) {
+	for i := 0; i < ptrs; i++ {
+		buf.WriteRune('&')
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if ptrs == 0 {
+			buf.WriteRune('*')
+		}
+		r.writeType(buf, 0, t.Elem())
+
+	case reflect.Interface:
+		if t.Name() != "" {
+			buf.WriteString(r.typeName(t))
+		} else {
+			buf.WriteString("interface{}")
+		}
+
+	case reflect.Array:
+		fmt.Fprintf(buf, "[%d]", t.Len())
+		r.writeType(buf, 0, t.Elem())
+
+	case reflect.Slice:
+		if t.Name() != "" {
+			buf.WriteString(r.typeName(t))
+			return
+		}
+		buf.WriteString("[]")
+		r.writeType(buf, 0, t.Elem())
+
+	case reflect.Map:
+		if t.Name() != "" {
+			buf.WriteString(r.typeName(t))
+			return
+		}
+		buf.WriteString("map[")
+		r.writeType(buf, 0, t.Key())
+		buf.WriteRune(']')
+		r.writeType(buf, 0, t.Elem())
+
+	default:
+		buf.WriteString(r.typeName(t))
+	}
+}
+
+// typeString returns the code for a type
+func (r *renderer) typeString(t reflect.Type) string {
+	buf := &bytes.Buffer{}
+	r.writeType(buf, 0, t)
+	return buf.String()
+}
+
+// typeName returns the qualified name of a named type
+func (r *renderer) typeName(t reflect.Type) string {
+	if t.Name() == "" {
+		return t.String()
+	}
+	return r.imports.qualify(t.PkgPath(), packageName(t), t.Name())
+}
+
+// ident returns the qualified identifier for name in the package at pkgPath, whose name is the last element of its path
+func (r *renderer) ident(pkgPath string, name string) string {
+	return r.imports.qualify(pkgPath, path.Base(pkgPath), name)
+}
+
+// isAnon returns whether the type of a value can be left out when it is an element of a composite literal, which is
+// when it is unnamed or a builtin type, as vrender decides it
+func isAnon(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Name() == "" || builtinTypeNames[t.Name()]
+}
+
+// isAnonKey returns whether the type of a map key can be left out, which is when a constant string or number can be
+// converted to it, as vrender decides it
+func isAnonKey(t reflect.Type) bool {
+	for _, constant := range []reflect.Type{stringType, reflect.TypeOf(0), reflect.TypeOf(uint(0)), reflect.TypeOf(0.0)} {
+		if constant.ConvertibleTo(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinTypeNames holds the names of the builtin boolean, numeric and string types
+var builtinTypeNames = map[string]bool{}
+
+func init() {
+	for k := reflect.Bool; k <= reflect.Complex128; k++ {
+		builtinTypeNames[k.String()] = true
+	}
+	builtinTypeNames[reflect.String.String()] = true
+}
+
+// renderBytes renders a byte slice as a conversion of a raw string, as vrender does, or of a quoted string when the
+// bytes can not be written as a raw string
+func renderBytes(b []byte) string {
+	if utf8.Valid(b) && !bytes.ContainsAny(b, "`\r") {
+		return "[]byte(`" + string(b) + "`)"
+	}
+	return fmt.Sprintf("[]byte(%q)", b)
+}
+
+// lessValue orders map keys so that maps are always printed the same way
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return renderValue(a) < renderValue(b)
+}
+
+// constantName returns the name of the constant a value is equal to, if it is a protobuf enum or a registered constant
+func (r *renderer) constantName(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	if isBasic(v.Kind()) {
+		registeredConstantsLock.RLock()
+		name, ok := registeredConstants[v.Interface()]
+		registeredConstantsLock.RUnlock()
+		if ok {
+			return name, true
+		}
+	}
+	if v.Kind() != reflect.Int32 || !v.Type().Implements(enumInterface) {
+		return "", false
+	}
+	enum := v.Interface().(protoreflect.Enum)
+	descriptor := enum.Descriptor()
+	value := descriptor.Values().ByNumber(enum.Number())
+	if value == nil {
+		return "", false
+	}
+	// Generated constants are prefixed with the enclosing message for nested enums, and the enum itself otherwise
+	goName := v.Type().Name()
+	prefix := goName
+	if _, nested := descriptor.Parent().(protoreflect.MessageDescriptor); nested {
+		prefix = strings.TrimSuffix(goName, "_"+string(descriptor.Name()))
+	}
+	return r.imports.qualify(v.Type().PkgPath(), packageName(v.Type()), fmt.Sprintf("%s_%s", prefix, value.Name())), true
+}
+
+// isBasic returns whether a kind is a boolean, numeric or string kind, which are the only kinds a constant can have
+func isBasic(k reflect.Kind) bool {
+	return (k >= reflect.Bool && k <= reflect.Complex128) || k == reflect.String
+}
+
+// packageName returns the name a type's package is referred to by
+func packageName(t reflect.Type) string {
+	return strings.TrimSuffix(t.String(), "."+t.Name())
+}
+
+// renderTime renders a time as the time.Date call that creates it
+func (r *renderer) renderTime(t time.Time) string {
+	if t.IsZero() {
+		return r.ident(timePath, "Time") + "{}"
+	}
+	t = t.UTC()
+	return fmt.Sprintf("%s(%d, %d, %d, %d, %d, %d, %d, %s)", r.ident(timePath, "Date"), t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), r.ident(timePath, "UTC"))
+}
+
+// renderDuration renders a duration as a multiple of the largest unit that divides it evenly, e.g. 5 * time.Second
+func (r *renderer) renderDuration(d time.Duration) string {
+	if d == 0 {
+		return r.ident(timePath, "Duration") + "(0)"
+	}
+	for _, u := range durationUnits {
+		if d%u.unit != 0 {
+			continue
+		}
+		if d == u.unit {
+			return r.ident(timePath, u.name)
+		}
+		return fmt.Sprintf("%d * %s", d/u.unit, r.ident(timePath, u.name))
+	}
+	return fmt.Sprintf("%s(%d)", r.ident(timePath, "Duration"), int64(d))
+}
+
+// asError returns the error held by v, if v holds a non-nil error
+func asError(v reflect.Value) (error, bool) {
+	if !v.IsValid() || !v.CanInterface() || !v.Type().Implements(errorInterface) {
+		return nil, false
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, false
+	}
+	err, ok := v.Interface().(error)
+	return err, ok && err != nil
+}
+
+// renderError converts an error to the code that would construct it.  Errors of an unknown type fall back to
+// fmt.Errorf with the error's message
+func (r *renderer) renderError(err error) string {
+	switch e := err.(type) {
+	case verrors.ServiceError:
+		return r.renderServiceError(e)
+	case *verrors.ServiceError:
+		return r.renderServiceError(*e)
+	}
+	if reflect.TypeOf(err) == errorsNewType {
+		return fmt.Sprintf("%s(%s)", r.ident(errorsPath, "New"), strconv.Quote(err.Error()))
+	}
+	if s, ok := status.FromError(err); ok {
+		return fmt.Sprintf("%s(%s, %s)", r.ident(statusPath, "Error"), r.renderCode(s.Code()), strconv.Quote(s.Message()))
+	}
+	return fmt.Sprintf("%s(%s)", r.ident(fmtPath, "Errorf"), quoteFormat(err.Error()))
+}
+
+// renderServiceError renders a verrors error as the verrors.New call that creates it
+func (r *renderer) renderServiceError(e verrors.ServiceError) string {
+	return fmt.Sprintf("%s(%s, %s)", r.ident(verrorsPath, "New"), r.ident(verrorsPath, e.ErrorType().String()), quoteFormat(e.Error()))
+}
+
+// renderCode converts a gRPC code to its named constant, or a conversion if the code is not a known constant
+func (r *renderer) renderCode(c codes.Code) string {
+	name := c.String()
+	if strings.HasPrefix(name, "Code(") {
+		return fmt.Sprintf("%s(%d)", r.ident(codesPath, "Code"), uint32(c))
+	}
+	return r.ident(codesPath, name)
+}
+
+// quoteFormat quotes a message so that it can be used as a format string that prints the message unchanged
+func quoteFormat(message string) string {
+	return strconv.Quote(strings.Replace(message, "%", "%%", -1))
+}
@@ -0,0 +1,130 @@
+package vmockhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/short-hop/vrender"
+	"github.com/stretchr/testify/assert"
+	"github.com/vendasta/gosdks/verrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type renderKey string
+
+type renderStruct struct {
+	Name     string
+	Count    int
+	Tags     []string
+	Child    *renderStruct
+	Values   map[string]int
+	Keyed    map[renderKey]bool
+	Anything interface{}
+}
+
+type renderCount int
+
+func TestRenderMatchesVrender(t *testing.T) {
+	values := []interface{}{
+		"text",
+		42,
+		renderCount(3),
+		[]byte("raw bytes"),
+		[]string{"a", "b"},
+		[]interface{}{1, "a", nil},
+		map[string]int{"b": 2, "a": 1},
+		map[renderKey]renderCount{"a": 1},
+		map[string]*renderStruct{"a": {Name: "A"}},
+		struct {
+			A int
+			B string
+			C renderKey
+		}{1, "b", "c"},
+		[]struct{ A int }{{1}, {2}},
+		&renderStruct{
+			Name:     "parent",
+			Count:    1,
+			Tags:     []string{"x"},
+			Child:    &renderStruct{Name: "child"},
+			Values:   map[string]int{"one": 1},
+			Keyed:    map[renderKey]bool{"k": true},
+			Anything: renderCount(2),
+		},
+		[]*renderStruct{{Name: "a"}, nil},
+		time.Date(2022, 3, 14, 19, 10, 30, 0, time.UTC),
+		time.Time{},
+		[]string(nil),
+		map[string]int(nil),
+	}
+	for _, value := range values {
+		assert.Equal(t, vrender.Render(value), renderValue(reflect.ValueOf(value)), "%#v", value)
+	}
+}
+
+func TestRenderBytesThatCanNotBeRaw(t *testing.T) {
+	assert.Equal(t, "[]byte(\"a`b\")", renderValue(reflect.ValueOf([]byte("a`b"))))
+	assert.Equal(t, `[]byte("\xff")`, renderValue(reflect.ValueOf([]byte{0xff})))
+}
+
+func TestRenderErrors(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{status.Error(codes.NotFound, "no account"), `status.Error(codes.NotFound, "no account")`},
+		{status.Error(codes.Code(99), "odd"), `status.Error(codes.Code(99), "odd")`},
+		{verrors.New(verrors.NotFound, "no %s", "account"), `verrors.New(verrors.NotFound, "no account")`},
+		{errors.New("plain"), `errors.New("plain")`},
+		{fmt.Errorf("wrapped: %w", errors.New("100%")), `fmt.Errorf("wrapped: 100%%")`},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, renderValue(reflect.ValueOf(c.err)))
+	}
+
+	returns := []error{nil, errors.New("in a slice")}
+	assert.Equal(t, `[]error{nil, errors.New("in a slice")}`, renderValue(reflect.ValueOf(returns)))
+}
+
+func TestRenderEnumsAndWellKnownTypes(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{structpb.NullValue_NULL_VALUE, "structpb.NullValue_NULL_VALUE"},
+		{descriptorpb.FieldDescriptorProto_TYPE_STRING, "descriptorpb.FieldDescriptorProto_TYPE_STRING"},
+		{descriptorpb.FieldDescriptorProto_Type(99), "descriptorpb.FieldDescriptorProto_Type(99)"},
+		{5 * time.Second, "5 * time.Second"},
+		{time.Minute, "time.Minute"},
+		{time.Duration(0), "time.Duration(0)"},
+		{1500 * time.Millisecond, "1500 * time.Millisecond"},
+		{timestamppb.New(time.Date(2022, 3, 14, 19, 10, 30, 0, time.UTC)), "timestamppb.New(time.Date(2022, 3, 14, 19, 10, 30, 0, time.UTC))"},
+		{durationpb.New(time.Hour), "durationpb.New(time.Hour)"},
+		{wrapperspb.String("ABC"), `wrapperspb.String("ABC")`},
+		{wrapperspb.Int64(7), "wrapperspb.Int64(7)"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, renderValue(reflect.ValueOf(c.value)))
+	}
+}
+
+func TestRegisterConstant(t *testing.T) {
+	defer func() {
+		registeredConstants = map[interface{}]string{}
+	}()
+
+	assert.NoError(t, RegisterConstant(renderCount(7), "renderSeven"))
+	assert.Equal(t, "[]vmockhelper.renderCount{renderSeven, vmockhelper.renderCount(8)}", renderValue(reflect.ValueOf([]renderCount{7, 8})))
+
+	assert.Error(t, RegisterConstant([]string{"not", "hashable"}, "slice"))
+	assert.Error(t, RegisterConstant(map[string]int{}, "map"))
+	assert.Error(t, RegisterConstant(nil, "nil"))
+}
@@ -0,0 +1,234 @@
+package vmockhelper
+
+import (
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// reportCall is a recorded call as shown in the HTML report
+type reportCall struct {
+	Index    int
+	Alias    string
+	Method   string
+	Source   string
+	Args     htmltemplate.HTML
+	Returns  htmltemplate.HTML
+	Expected string
+}
+
+// reportTest is the calls made by one test, as shown in the HTML report
+type reportTest struct {
+	Name  string
+	Calls []reportCall
+}
+
+// WriteHTMLReport writes a self-contained HTML page to path showing every call recorded since Record was called,
+// grouped by test and alias.  Each call shows its arguments and return values as a collapsible tree, the expected call
+// with a button to copy it, and whether the values returned were real, mocked, filled or zero
+func WriteHTMLReport(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeHTMLReport(file, recorded())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeHTMLReport(w io.Writer, calls []Call) error {
+	var tests []*reportTest
+	byName := map[string]*reportTest{}
+	for i, call := range calls {
+		test, ok := byName[call.test]
+		if !ok {
+			test = &reportTest{Name: call.test}
+			if test.Name == "" {
+				test.Name = "Calls outside of a test"
+			}
+			byName[call.test] = test
+			tests = append(tests, test)
+		}
+		test.Calls = append(test.Calls, reportCall{
+			Index:    i + 1,
+			Alias:    call.alias,
+			Method:   call.method,
+			Source:   call.source,
+			Args:     valueTrees("In", call.args),
+			Returns:  valueTrees("Out", call.returns),
+			Expected: expectedCode(call),
+		})
+	}
+	for _, test := range tests {
+		sort.SliceStable(test.Calls, func(i, j int) bool {
+			return test.Calls[i].Alias < test.Calls[j].Alias
+		})
+	}
+	return reportTemplate.Execute(w, tests)
+}
+
+// expectedCode returns the expected call for a recorded call, formatted across multiple lines
+func expectedCode(call Call) string {
+	r := &renderer{multiline: true, imports: newImportSet("")}
+	expected := strings.TrimSpace(fmt.Sprintf(mockFMT, call.alias, call.method, valuesToCodeString(r, call.args), valuesToCodeString(r, call.returns)))
+	return strings.TrimSpace(formatCode(expected))
+}
+
+// valueTrees returns a collapsible tree of each value, named with prefix and its position, skipping contexts
+func valueTrees(prefix string, values []reflect.Value) htmltemplate.HTML {
+	var trees strings.Builder
+	position := 0
+	for _, value := range values {
+		if value.IsValid() && isContext(value) {
+			continue
+		}
+		position++
+		writeValueTree(&trees, fmt.Sprintf("%s%d", prefix, position), value, map[uintptr]bool{})
+	}
+	return htmltemplate.HTML(trees.String())
+}
+
+// writeValueTree writes v as a tree of details elements, one for every pointer, struct, slice, array and map that is
+// not empty, with every other value as a leaf showing its code
+func writeValueTree(w *strings.Builder, name string, v reflect.Value, visiting map[uintptr]bool) {
+	leaf := func() {
+		fmt.Fprintf(w, `<div class="leaf"><span class="name">%s</span> %s</div>`, html.EscapeString(name), html.EscapeString(renderValue(v)))
+	}
+	v = indirectInterface(v)
+	if !v.IsValid() {
+		leaf()
+		return
+	}
+	if _, ok := asError(v); ok {
+		leaf()
+		return
+	}
+
+	var children func()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visiting[v.Pointer()] {
+			leaf()
+			return
+		}
+		visiting[v.Pointer()] = true
+		defer delete(visiting, v.Pointer())
+		writeValueTree(w, name, v.Elem(), visiting)
+		return
+	case reflect.Struct:
+		if v.Type() == timeType || v.NumField() == 0 {
+			leaf()
+			return
+		}
+		children = func() {
+			for i := 0; i < v.NumField(); i++ {
+				if v.Field(i).CanInterface() {
+					writeValueTree(w, v.Type().Field(i).Name, v.Field(i), visiting)
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 || isBasic(v.Type().Elem().Kind()) {
+			leaf()
+			return
+		}
+		children = func() {
+			for i := 0; i < v.Len(); i++ {
+				writeValueTree(w, fmt.Sprintf("[%d]", i), v.Index(i), visiting)
+			}
+		}
+	case reflect.Map:
+		if v.Len() == 0 {
+			leaf()
+			return
+		}
+		children = func() {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return lessValue(keys[i], keys[j])
+			})
+			for _, key := range keys {
+				writeValueTree(w, "["+renderValue(key)+"]", v.MapIndex(key), visiting)
+			}
+		}
+	default:
+		leaf()
+		return
+	}
+	fmt.Fprintf(w, `<details><summary><span class="name">%s</span> %s</summary>`, html.EscapeString(name), html.EscapeString(v.Type().String()))
+	children()
+	w.WriteString(`</details>`)
+}
+
+var reportTemplate = htmltemplate.Must(htmltemplate.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>vmockhelper report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; }
+pre, .leaf, summary { font-family: monospace; font-size: 12px; }
+details { margin-left: 1em; }
+.leaf { margin-left: 2em; word-break: break-all; }
+.name { font-weight: bold; }
+.real { color: #0a7d2c; }
+.mocked { color: #1f5fbf; }
+.filled { color: #b26b00; }
+.zero { color: #888; }
+</style>
+</head>
+<body>
+<h1>vmockhelper report</h1>
+{{range .}}
+<h2>{{.Name}}</h2>
+<table>
+<thead><tr><th>#</th><th>Alias</th><th>Method</th><th>Source</th><th>Arguments</th><th>Returns</th><th>Expected call</th></tr></thead>
+<tbody>
+{{range .Calls}}<tr>
+<td data-sort="{{.Index}}">{{.Index}}</td>
+<td>{{.Alias}}</td>
+<td>{{.Method}}</td>
+<td class="{{.Source}}">{{.Source}}</td>
+<td>{{.Args}}</td>
+<td>{{.Returns}}</td>
+<td><button onclick="copyExpected(this)">Copy</button><details><summary>show</summary><pre>{{.Expected}}</pre></details></td>
+</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+<script>
+function copyExpected(button) {
+	navigator.clipboard.writeText(button.parentElement.querySelector("pre").textContent);
+	button.textContent = "Copied";
+	setTimeout(function () { button.textContent = "Copy"; }, 1000);
+}
+document.querySelectorAll("th").forEach(function (header) {
+	header.addEventListener("click", function () {
+		var table = header.closest("table");
+		var column = Array.prototype.indexOf.call(header.parentElement.children, header);
+		var ascending = header.dataset.order !== "asc";
+		header.dataset.order = ascending ? "asc" : "desc";
+		var rows = Array.prototype.slice.call(table.tBodies[0].rows);
+		rows.sort(function (a, b) {
+			var x = a.cells[column].dataset.sort || a.cells[column].textContent;
+			var y = b.cells[column].dataset.sort || b.cells[column].textContent;
+			var compared = isNaN(x) || isNaN(y) ? x.localeCompare(y) : x - y;
+			return ascending ? compared : -compared;
+		});
+		rows.forEach(function (row) { table.tBodies[0].appendChild(row); });
+	});
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,140 @@
+package vmockhelper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/mock/gomock"
+)
+
+// shadowCalls tracks the calls to real services started by ShadowRealAndCompare that have not finished yet
+var shadowCalls sync.WaitGroup
+
+var shadowDrifts []Drift
+var shadowDriftsLock sync.Mutex
+
+// ShadowRealAndCompare lets the expectations a test has already set on a gomock object answer each call, while also
+// making the same call to the real service in the background.  Any difference between what the expectation returned and
+// what the real service returned is printed, so the test stays deterministic while still showing when its fixtures are
+// stale.  Only expectations set before ShadowRealAndCompare is called answer calls, and any set afterwards are reported
+// as missing.  Like UseRealAndPrintExpected, it never calls a real production service or a real service in CI unless
+// allowed, and options can limit which methods are relayed.  Call WaitForShadowCalls at the end of the test to wait for
+// the real calls and collect the differences.  When the mock was created with a *testing.T, the test also waits for
+// them before it ends
+func ShadowRealAndCompare(gomockObject interface{}, realService interface{}, mockAlias string, opts ...RelayOption) {
+	reporter := testReporter(gomockObject)
+	if reason := realRelayBlocked(); reason != "" {
+		emitf("!!! vmockhelper: %s will not be shadowed by the real service because %s !!!", mockAlias, reason)
+		return
+	}
+	relay := newRelayConfig(opts)
+	if err := relay.checkAdapters(gomockObject); err != nil {
+		reportError(reporter, fmt.Sprintf("vmockhelper: %s can not be shadowed: %s", mockAlias, err.Error()))
+		return
+	}
+	expectations, err := controller(gomockObject)
+	if err != nil {
+		reportError(reporter, fmt.Sprintf("vmockhelper: %s can not be shadowed: %s", mockAlias, err.Error()))
+		return
+	}
+	relay.budget.shareWithTest(reporter)
+	if cleanuper, ok := reporter.(interface{ Cleanup(func()) }); ok {
+		// The real calls report through the test, which must not happen after it has ended
+		cleanuper.Cleanup(shadowCalls.Wait)
+	}
+	shadowed := map[string]bool{}
+	for _, methodName := range mockMethods(gomockObject) {
+		if !relay.relays(methodName) {
+			continue
+		}
+		if !relay.method(realService, methodName).IsValid() {
+			emitf("vmockhelper: the real service for %s has no method %s", mockAlias, relay.realName(methodName))
+			continue
+		}
+		shadowed[methodName] = true
+	}
+
+	// The expectations stay with the controller they were set on, and the mock is given a new controller that passes
+	// every call on to them before shadowing it
+	setController(gomockObject, gomock.NewController(expectations.T))
+	handleAllMethods(gomockObject, mockAlias, func(methodName string, methodType reflect.Type, args []reflect.Value) ([]reflect.Value, string) {
+		returns := callExpected(expectations, gomockObject, methodName, methodType, args)
+		if shadowed[methodName] {
+			shadow(reporter, relay, realService, mockAlias, methodName, methodType, args, returns)
+		}
+		return returns, sourceMocked
+	})
+}
+
+// WaitForShadowCalls waits for every real call started by ShadowRealAndCompare to finish, and returns the differences
+// found since it was last called
+func WaitForShadowCalls() []Drift {
+	shadowCalls.Wait()
+	shadowDriftsLock.Lock()
+	defer shadowDriftsLock.Unlock()
+	drifts := shadowDrifts
+	shadowDrifts = nil
+	return drifts
+}
+
+// callExpected answers a call to a mock method with the expectations set on ctrl, the same way the mock's own method
+// would, spreading the last argument of a variadic method into the arguments matched against them
+func callExpected(ctrl *gomock.Controller, gomockObject interface{}, methodName string, methodType reflect.Type, args []reflect.Value) []reflect.Value {
+	var callArgs []interface{}
+	for i, arg := range args {
+		if methodType.IsVariadic() && i == len(args)-1 {
+			for j := 0; j < arg.Len(); j++ {
+				callArgs = append(callArgs, arg.Index(j).Interface())
+			}
+			continue
+		}
+		callArgs = append(callArgs, arg.Interface())
+	}
+	rets := ctrl.Call(gomockObject, methodName, callArgs...)
+	returns := make([]reflect.Value, methodType.NumOut())
+	for i := range returns {
+		if i < len(rets) && rets[i] != nil {
+			returns[i] = reflect.ValueOf(rets[i])
+		} else {
+			returns[i] = reflect.Zero(methodType.Out(i))
+		}
+	}
+	return returns
+}
+
+// shadow calls the real service in the background with the arguments a mock received, and compares what it returns
+// with what the mock returned.  Contexts are replaced with context.Background(), since the test's context may be
+// cancelled before the real call finishes.  A panic is reported as a test failure without changing what the mock
+// returns
+func shadow(reporter gomock.TestReporter, relay *relayConfig, realService interface{}, mockAlias string, methodName string, methodType reflect.Type, args []reflect.Value, returns []reflect.Value) {
+	if !relay.budget.spend() {
+		relay.budget.exhausted(reporter, mockAlias, methodName)
+		return
+	}
+	realArgs := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		realArgs[i] = arg
+		if isContext(arg) {
+			realArgs[i] = reflect.ValueOf(context.Background())
+		}
+	}
+	// The code under test may change the arguments or return values before the real call finishes
+	realArgs = snapshot(realArgs)
+	mocked := snapshot(returns)
+
+	shadowCalls.Add(1)
+	go func() {
+		defer shadowCalls.Done()
+		defer recoverCall(reporter, mockAlias, methodName, methodType, realArgs, nil)
+		_, real, note := relay.callReal(realService, methodName, realArgs)
+		if note != "" {
+			emitf("vmockhelper: %s.%s: %s", mockAlias, methodName, note)
+		}
+		drifts := compareReturns(mockAlias, methodName, redact(mocked), redact(real))
+		shadowDriftsLock.Lock()
+		shadowDrifts = append(shadowDrifts, drifts...)
+		shadowDriftsLock.Unlock()
+	}()
+}
@@ -0,0 +1,81 @@
+package vmockhelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowRealAndCompare(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	useBuffer(t)
+	real := &fakeService{name: "Bob"}
+	mock := NewMockTestService(gomock.NewController(t))
+	mock.EXPECT().Get(gomock.Any(), "1").Return(&testThing{ID: "1", Name: "Alice"}, nil)
+	mock.EXPECT().List(gomock.Any(), "1", "2").Return([]*testThing{{ID: "1", Name: "Bob"}, {ID: "2", Name: "Bob"}}, nil)
+	ShadowRealAndCompare(mock, real, "svc")
+
+	thing, err := mock.Get(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, &testThing{ID: "1", Name: "Alice"}, thing)
+	things, err := mock.List(context.Background(), "1", "2")
+	assert.NoError(t, err)
+	assert.Len(t, things, 2)
+
+	drifts := WaitForShadowCalls()
+	assert.Equal(t, []Drift{{Alias: "svc", Method: "Get", Path: "Out1.Name", Expected: `"Alice"`, Actual: `"Bob"`}}, drifts)
+	assert.ElementsMatch(t, []string{"Get", "List"}, real.called())
+}
+
+func TestShadowRealAndCompareOnlyShadowsRelayedMethods(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	useBuffer(t)
+	real := &fakeService{name: "Alice"}
+	mock := NewMockTestService(gomock.NewController(t))
+	mock.EXPECT().Get(gomock.Any(), "1").Return(&testThing{ID: "1", Name: "Alice"}, nil)
+	mock.EXPECT().Create(gomock.Any(), gomock.Any()).Return("ID-1", nil)
+	ShadowRealAndCompare(mock, real, "svc", DenyMethods("Create"))
+
+	_, _ = mock.Get(context.Background(), "1")
+	id, _ := mock.Create(context.Background(), &testThing{Name: "new"})
+
+	assert.Equal(t, "ID-1", id)
+	assert.Empty(t, WaitForShadowCalls())
+	assert.Equal(t, []string{"Get"}, real.called())
+}
+
+func TestShadowRealAndCompareRedactsDrift(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	Clear()
+	Redact(RedactFields("Name"))
+	defer Redact()
+	mock := NewMockTestService(gomock.NewController(t))
+	mock.EXPECT().Get(gomock.Any(), "1").Return(&testThing{ID: "1", Name: "Alice Secret"}, nil)
+	ShadowRealAndCompare(mock, &fakeService{name: "Bob Secret"}, "svc")
+
+	_, _ = mock.Get(context.Background(), "1")
+
+	assert.Equal(t, []Drift{{Alias: "svc", Method: "Get", Path: "Out1.Name", Expected: `"redacted-1"`, Actual: `"redacted-2"`}}, WaitForShadowCalls())
+	assert.NotContains(t, buffer.String(), "Secret")
+}
+
+func TestShadowRealAndCompareInCI(t *testing.T) {
+	skipIfAllowedByTag(t)
+	outsideCI(t)
+	t.Setenv("CI", "true")
+	buffer := useBuffer(t)
+	real := &fakeService{name: "Bob"}
+	mock := NewMockTestService(gomock.NewController(t))
+	mock.EXPECT().Get(gomock.Any(), "1").Return(&testThing{ID: "1", Name: "Alice"}, nil)
+	ShadowRealAndCompare(mock, real, "svc")
+
+	thing, _ := mock.Get(context.Background(), "1")
+
+	assert.Equal(t, "Alice", thing.Name)
+	assert.Empty(t, WaitForShadowCalls())
+	assert.Empty(t, real.called())
+	assert.Contains(t, buffer.String(), "svc will not be shadowed by the real service because the CI environment variable")
+}
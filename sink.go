@@ -0,0 +1,194 @@
+package vmockhelper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vendasta/gosdks/logging"
+)
+
+// Sink receives everything vmockhelper prints: expected calls, test cases, and differences from the real service
+type Sink interface {
+	Print(output string)
+}
+
+// sink is where printed output goes.  By default it is logged as an alert
+var sink Sink = LoggingSink()
+var sinkLock sync.RWMutex
+
+// SetSink sends everything vmockhelper prints to s, including warnings about calls it skipped or could not make.
+// Passing nil goes back to logging it as an alert
+func SetSink(s Sink) {
+	if s == nil {
+		s = LoggingSink()
+	}
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+	sink = s
+}
+
+// currentSink returns the sink output is sent to
+func currentSink() Sink {
+	sinkLock.RLock()
+	defer sinkLock.RUnlock()
+	return sink
+}
+
+// emit sends output to the sink
+func emit(output string) {
+	s := currentSink()
+	if ts, ok := s.(testSink); ok {
+		ts.t.Helper()
+	}
+	s.Print(output)
+}
+
+// emitf formats a message about something vmockhelper did, like skipping a call, and sends it to the sink on a line of
+// its own
+func emitf(format string, args ...interface{}) {
+	if ts, ok := currentSink().(testSink); ok {
+		ts.t.Helper()
+	}
+	emit(fmt.Sprintf("\n"+format+"\n", args...))
+}
+
+// emitRequested sends output that was asked for, like a test case, to the sink.  When the sink discards output, it is
+// sent where the discarding sink sends requested output instead
+func emitRequested(output string) {
+	s := currentSink()
+	if discard, ok := s.(discardSink); ok {
+		s = discard.requested
+	}
+	if ts, ok := s.(testSink); ok {
+		ts.t.Helper()
+	}
+	s.Print(output)
+}
+
+// discarding returns whether the sink throws output away, in which case expected calls do not need to be rendered
+func discarding() bool {
+	_, discard := currentSink().(discardSink)
+	return discard
+}
+
+// DiscardSink throws away what is printed as mocks are called.  Expected calls are not rendered at all while it is the
+// sink, unless DedupeExpected needs to count them, which keeps tests that only record calls fast.  What is printed on
+// request by PrintTestCase, PrintSequenceDiagram, PrintExpectedSummary and PrintBudgetSummary, and the summary
+// DedupeExpected prints when a test ends, is sent to requested instead, or logged as an alert if requested is not
+// given, so that calls made under DiscardSink can still be printed
+func DiscardSink(requested ...Sink) Sink {
+	if len(requested) > 0 && requested[0] != nil {
+		return discardSink{requested: requested[0]}
+	}
+	return discardSink{requested: LoggingSink()}
+}
+
+type discardSink struct {
+	requested Sink
+}
+
+func (discardSink) Print(string) {}
+
+// SinkFunc lets a function be used as a Sink
+type SinkFunc func(output string)
+
+// Print calls f with output
+func (f SinkFunc) Print(output string) {
+	f(output)
+}
+
+// LoggingSink logs output as an alert through the gosdks logging package
+func LoggingSink() Sink {
+	return SinkFunc(func(output string) {
+		logging.Alertf(context.Background(), "%s", output)
+	})
+}
+
+// TestSink logs output with t.Log, so it is shown with the test's output and only when the test fails or is run with
+// -v.  The sink is marked as a test helper, so the output is attributed to the vmockhelper function that printed it
+// rather than to the sink
+func TestSink(t testing.TB) Sink {
+	return testSink{t: t}
+}
+
+type testSink struct {
+	t testing.TB
+}
+
+func (s testSink) Print(output string) {
+	s.t.Helper()
+	s.t.Log(output)
+}
+
+// WriterSink writes output to w, followed by a newline if it does not end with one
+func WriterSink(w io.Writer) Sink {
+	lock := sync.Mutex{}
+	return SinkFunc(func(output string) {
+		if !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		_, _ = io.WriteString(w, output)
+	})
+}
+
+// FileSink appends output to the file at path, creating it if it does not exist.  The file is only open while output
+// is written to it
+func FileSink(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	lock := sync.Mutex{}
+	return SinkFunc(func(output string) {
+		lock.Lock()
+		defer lock.Unlock()
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			logging.Errorf(context.Background(), "vmockhelper: could not write to %s: %s", path, err.Error())
+			return
+		}
+		defer file.Close()
+		WriterSink(file).Print(output)
+	}), nil
+}
+
+// BufferSink keeps output in memory, for tests that check what was printed
+type BufferSink struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+// Print adds output to the buffer, followed by a newline if it does not end with one
+func (b *BufferSink) Print(output string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buffer.WriteString(output)
+	if !strings.HasSuffix(output, "\n") {
+		b.buffer.WriteString("\n")
+	}
+}
+
+// String returns everything printed so far
+func (b *BufferSink) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.String()
+}
+
+// Reset forgets everything printed so far
+func (b *BufferSink) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buffer.Reset()
+}
@@ -0,0 +1,83 @@
+package vmockhelper
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingError counts how many times it is rendered
+type countingError struct {
+	renders int32
+}
+
+func (e *countingError) Error() string {
+	atomic.AddInt32(&e.renders, 1)
+	return "counted"
+}
+
+func TestDiscardSinkSkipsRendering(t *testing.T) {
+	SetSink(DiscardSink())
+	defer SetSink(nil)
+	err := &countingError{}
+	mock := NewMockTestService(gomock.NewController(t))
+	MockCallsAndPrintExpected(mock, "svc", nil, err)
+
+	_, returned := mock.Get(context.Background(), "1")
+
+	assert.Equal(t, err, returned)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&err.renders))
+
+	buffer := useBuffer(t)
+	_, _ = mock.Get(context.Background(), "1")
+	assert.Contains(t, buffer.String(), `svc.EXPECT().Get(gomock.Any(), "1").Return(nil, fmt.Errorf("counted"))`)
+	assert.NotEqual(t, int32(0), atomic.LoadInt32(&err.renders))
+}
+
+func TestDiscardSinkSendsRequestedOutput(t *testing.T) {
+	requested := &BufferSink{}
+	SetSink(DiscardSink(requested))
+	defer SetSink(nil)
+	Clear()
+	defer Clear()
+	mock := NewMockTestService(gomock.NewController(t))
+	MockCallsAndPrintExpected(mock, "svc")
+	Record()
+
+	_, _ = mock.Get(context.Background(), "1")
+	assert.Empty(t, requested.String())
+
+	PrintTestCase()
+	assert.Contains(t, requested.String(), "cases := []*testCase{")
+}
+
+func TestDedupeSummaryUnderDiscardSink(t *testing.T) {
+	requested := &BufferSink{}
+	SetSink(DiscardSink(requested))
+	defer SetSink(nil)
+	DedupeExpected(true)
+	defer DedupeExpected(false)
+
+	t.Run("calls", func(t *testing.T) {
+		mock := NewMockTestService(gomock.NewController(t))
+		MockCallsAndPrintExpected(mock, "svc")
+		_, _ = mock.Get(context.Background(), "1")
+		_, _ = mock.Get(context.Background(), "1")
+		assert.Empty(t, requested.String())
+	})
+
+	assert.Contains(t, requested.String(), "// Distinct expected calls made by TestDedupeSummaryUnderDiscardSink/calls")
+	assert.Contains(t, requested.String(), `svc.EXPECT().Get(gomock.Any(), "1").Return(nil, nil).Times(2)`)
+}
+
+func TestWriterSink(t *testing.T) {
+	var written bytes.Buffer
+	sink := WriterSink(&written)
+	sink.Print("one")
+	sink.Print("two\n")
+	assert.Equal(t, "one\ntwo\n", written.String())
+}
@@ -0,0 +1,120 @@
+package vmockhelper
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// pointerKey identifies a pointer by its address and type.  The type is part of the key since a struct and its first
+// field, or two zero-size values, can share an address
+type pointerKey struct {
+	address uintptr
+	t       reflect.Type
+}
+
+// snapshot deep copies values, so that changes the code under test makes to them after a call do not change what was
+// recorded
+func snapshot(values []reflect.Value) []reflect.Value {
+	c := &copier{copies: map[pointerKey]reflect.Value{}}
+	var copies []reflect.Value
+	for _, value := range values {
+		copies = append(copies, c.copy(value))
+	}
+	return copies
+}
+
+// copier deep copies values, replacing the parts of them selected by its redaction rules.  Protobuf messages are copied
+// with proto.Clone when there are no rules, and contexts are not copied
+type copier struct {
+	// copies maps pointers to their copies, so that shared and recursive pointers are copied once
+	copies map[pointerKey]reflect.Value
+	// rules are the redaction rules applied to the copies, if any
+	rules []RedactionRule
+}
+
+func (c *copier) copy(v reflect.Value) reflect.Value {
+	if !v.IsValid() || !v.CanInterface() || isContext(v) {
+		return v
+	}
+	if c.redactsType(v.Type()) {
+		return c.replace(v)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := pointerKey{address: v.Pointer(), t: v.Type()}
+		if copied, ok := c.copies[key]; ok {
+			return copied
+		}
+		// Redacting a message needs its fields, so it can only be cloned when there are no rules
+		if message, ok := v.Interface().(proto.Message); ok && len(c.rules) == 0 {
+			copied := reflect.ValueOf(proto.Clone(message))
+			c.copies[key] = copied
+			return copied
+		}
+		copied := reflect.New(v.Type().Elem())
+		c.copies[key] = copied
+		copied.Elem().Set(c.copy(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(c.copy(v.Elem()))
+		return copied
+	case reflect.Struct:
+		// Unexported fields are copied as they are, since they can't be set and are not printed
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !copied.Field(i).CanSet() {
+				continue
+			}
+			if c.redactsField(v.Type().Field(i).Name) {
+				copied.Field(i).Set(c.replace(v.Field(i)))
+			} else {
+				copied.Field(i).Set(c.copy(v.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		// Keys are kept as they are, since a copy of a pointer key would not be the same key, but are redacted like
+		// values, since personal information like email addresses is often used as a key
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			if len(c.rules) > 0 {
+				copied.SetMapIndex(c.copy(key), c.copy(v.MapIndex(key)))
+			} else {
+				copied.SetMapIndex(key, c.copy(v.MapIndex(key)))
+			}
+		}
+		return copied
+	case reflect.String:
+		if len(c.rules) > 0 {
+			return c.redactString(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package vmockhelper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type snapshotNode struct {
+	Name string
+	Next *snapshotNode
+}
+
+type snapshotPair struct {
+	Inner  snapshotNode
+	Ptr    *snapshotNode
+	Inner2 *snapshotNode
+}
+
+// snapshotOne snapshots a single value
+func snapshotOne(value interface{}) interface{} {
+	return snapshot([]reflect.Value{reflect.ValueOf(value)})[0].Interface()
+}
+
+func TestRecordedCallsKeepTheValuesOfTheCall(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	SetSink(DiscardSink())
+	defer SetSink(nil)
+	Clear()
+	defer Clear()
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, &fakeService{name: "returned"}, "svc")
+	Record()
+
+	request := &testThing{Name: "sent", Tags: []string{"a"}}
+	_, _ = mock.Create(context.Background(), request)
+	request.Name = "changed"
+	request.Tags[0] = "changed"
+	response, _ := mock.Get(context.Background(), "1")
+	response.Name = "changed"
+
+	calls := recorded()
+	assert.Equal(t, &testThing{Name: "sent", Tags: []string{"a"}}, calls[0].args[1].Interface())
+	assert.Equal(t, &testThing{ID: "1", Name: "returned"}, calls[1].returns[0].Interface())
+}
+
+func TestSnapshotCopiesSharedPointersOnce(t *testing.T) {
+	shared := &snapshotNode{Name: "shared"}
+	shared.Next = shared
+	original := []*snapshotNode{shared, shared}
+
+	copied := snapshotOne(original).([]*snapshotNode)
+	shared.Name = "changed"
+
+	assert.Equal(t, "shared", copied[0].Name)
+	assert.Same(t, copied[0], copied[1])
+	assert.Same(t, copied[0], copied[0].Next)
+}
+
+func TestSnapshotTellsPointersAtTheSameAddressApart(t *testing.T) {
+	pair := &snapshotPair{Inner: snapshotNode{Name: "inner"}}
+	// A pointer to a struct and a pointer to its first field have the same address
+	pair.Ptr = &pair.Inner
+	pair.Inner2 = &snapshotNode{Name: "other"}
+
+	copied := snapshotOne(pair).(*snapshotPair)
+
+	assert.Equal(t, "inner", copied.Ptr.Name)
+	assert.Equal(t, "other", copied.Inner2.Name)
+}
+
+func TestSnapshotClonesMessagesAndKeepsContexts(t *testing.T) {
+	timestamp := timestamppb.New(time.Date(2022, 3, 14, 19, 10, 30, 0, time.UTC))
+	ctx := context.WithValue(context.Background(), snapshotNode{}, "value")
+
+	copies := snapshot([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(timestamp)})
+	timestamp.Seconds = 0
+
+	assert.Equal(t, ctx, copies[0].Interface())
+	assert.Equal(t, int64(1647285030), copies[1].Interface().(*timestamppb.Timestamp).Seconds)
+}
+
+func TestSnapshotKeepsPointerMapKeys(t *testing.T) {
+	key := &snapshotNode{Name: "key"}
+	copied := snapshotOne(map[*snapshotNode]int{key: 1}).(map[*snapshotNode]int)
+
+	assert.Equal(t, 1, copied[key])
+}
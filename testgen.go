@@ -2,10 +2,9 @@ package vmockhelper
 
 import (
 	"fmt"
+	"path"
 	"reflect"
 	"strings"
-
-	"github.com/short-hop/vrender"
 )
 
 type testType struct {
@@ -18,8 +17,8 @@ func (t testType) NameAndTypeString() string {
 	return fmt.Sprintf("%s %s", t.name, t.valueType)
 }
 
-func (t testType) AssignedZeroValueString() string {
-	return fmt.Sprintf("%s: %s", t.name, vrender.Render(t.value))
+func (t testType) AssignedZeroValueString(r *renderer) string {
+	return fmt.Sprintf("%s: %s", t.name, r.code(reflect.ValueOf(t.value)))
 }
 
 // GenerateTestTemplate generates a test template for a given service and method
@@ -31,6 +30,13 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 	sType := reflect.TypeOf(s)
 	//packageName = sType.PkgPath()
 
+	// The test is written in the same package as the service, and the packages the template itself uses are added
+	// first so that they keep their own names
+	r := &renderer{imports: newImportSet(sType.Elem().PkgPath())}
+	for _, pkgPath := range []string{"context", "testing", gomockPath, "github.com/stretchr/testify/assert", "github.com/short-hop/vmockhelper"} {
+		r.imports.alias(pkgPath, path.Base(pkgPath))
+	}
+
 	method, found := sType.MethodByName(methodName)
 	if !found {
 		panic("Method not found")
@@ -45,7 +51,7 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 		//v.SetZero()
 		inputs = append(inputs, testType{
 			name:      fmt.Sprintf("%sInput%d", methodName, i),
-			valueType: method.Type.In(i).String(),
+			valueType: r.typeString(method.Type.In(i)),
 			value:     reflect.New(method.Type.In(i)).Elem().Interface(),
 		})
 	}
@@ -55,7 +61,7 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 	for _, in := range inputs {
 		inputTypes += fmt.Sprintf("%s\n", in.NameAndTypeString())
 		inputNames = append(inputNames, fmt.Sprintf("c.%s", in.name))
-		testValues += fmt.Sprintf("%s,\n", in.AssignedZeroValueString())
+		testValues += fmt.Sprintf("%s,\n", in.AssignedZeroValueString(r))
 	}
 
 	var outputTypes string
@@ -65,7 +71,7 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 	for i := 0; i < numberOfOutputParameters; i++ {
 		outputs = append(outputs, testType{
 			name:      fmt.Sprintf("expectedOut%d", i+1),
-			valueType: method.Type.Out(i).String(),
+			valueType: r.typeString(method.Type.Out(i)),
 			value:     reflect.New(method.Type.Out(i)).Elem().Interface(),
 		})
 		outputNames = append(outputNames, fmt.Sprintf("out%d", i+1))
@@ -74,7 +80,7 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 
 	for _, out := range outputs {
 		outputTypes += fmt.Sprintf("%s\n", out.NameAndTypeString())
-		testValues += fmt.Sprintf("%s,\n", out.AssignedZeroValueString())
+		testValues += fmt.Sprintf("%s,\n", out.AssignedZeroValueString(r))
 	}
 
 	outputTypes = strings.TrimSuffix(outputTypes, "\n")
@@ -88,13 +94,12 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 		fieldEntry = strings.ReplaceAll(fieldEntry, "{{interfaceName}}", field.Type.Name())
 		fields = append(fields, fieldEntry)
 
-		if len(strings.Split(field.Type.String(), ".")) > 1 {
-			interfaceName := strings.Split(field.Type.String(), ".")[1]
-			interfacePackageName := strings.Split(field.Type.String(), ".")[0]
+		if field.Type.PkgPath() != "" && field.Type.PkgPath() != r.imports.local {
+			interfacePackage := r.imports.alias(field.Type.PkgPath(), packageName(field.Type))
 
-			mockEntry := strings.ReplaceAll(mockTemplate, "{{interfaceName}}", interfaceName)
+			mockEntry := strings.ReplaceAll(mockTemplate, "{{interfaceName}}", field.Type.Name())
 			mockEntry = strings.ReplaceAll(mockEntry, "{{fieldName}}", field.Name)
-			mockEntry = strings.ReplaceAll(mockEntry, "{{interfacePackage}}", interfacePackageName+".")
+			mockEntry = strings.ReplaceAll(mockEntry, "{{interfacePackage}}", interfacePackage+".")
 			mocks = append(mocks, mockEntry)
 		} else {
 			mockEntry := strings.ReplaceAll(mockTemplate, "{{interfaceName}}", field.Type.Name())
@@ -105,7 +110,8 @@ func GenerateTestTemplate(s interface{}, methodName string) {
 		mockHelpers = append(mockHelpers, strings.ReplaceAll(mockHelperTemplate, "{{fieldName}}", field.Name))
 	}
 
-	t := strings.ReplaceAll(template, "{{mocks}}", strings.Join(mocks, "\n"))
+	t := r.imports.block() + template
+	t = strings.ReplaceAll(t, "{{mocks}}", strings.Join(mocks, "\n"))
 	t = strings.ReplaceAll(t, "{{inputs}}", strings.Join(inputNames, ", "))
 	t = strings.ReplaceAll(t, "{{outputTypes}}", outputTypes)
 	t = strings.ReplaceAll(t, "{{inputTypes}}", inputTypes)
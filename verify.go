@@ -0,0 +1,258 @@
+package vmockhelper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+// Expectation is a call a test expects a mock to receive, along with what the mock returns
+type Expectation struct {
+	Method  string
+	Args    []interface{}
+	Returns []interface{}
+}
+
+// Drift is a difference between what a mock returns and what the real service returns for the same call
+type Drift struct {
+	Alias  string
+	Method string
+	// Path is the return value and fields leading to the difference, like Out1.NAPData.CompanyName
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s.%s %s: expected %s, real service returned %s", d.Alias, d.Method, d.Path, d.Expected, d.Actual)
+}
+
+// Verify calls the real service with the arguments of each expectation, the same way UseRealAndPrintExpected does, and
+// prints and returns every field where the real service returned something different from the expectation.  Running it
+// against Demo shows which mocks have drifted from what the service actually returns.  Contexts in the arguments are
+// replaced with context.Background(), and the differences are redacted with the rules set by Redact
+func Verify(realService interface{}, mockAlias string, expectations []Expectation, opts ...RelayOption) []Drift {
+	var calls []Call
+	for _, expectation := range expectations {
+		call := Call{method: expectation.Method, alias: mockAlias}
+		for _, arg := range expectation.Args {
+			call.args = append(call.args, reflect.ValueOf(arg))
+		}
+		for _, ret := range expectation.Returns {
+			call.returns = append(call.returns, reflect.ValueOf(ret))
+		}
+		// Expectations are redacted like recorded calls, so that redacted values never show up in the differences
+		call.returns = redact(call.returns)
+		calls = append(calls, call)
+	}
+	return verifyCalls(realService, mockAlias, calls, opts)
+}
+
+// VerifyRecorded works like Verify for every call to mockAlias recorded since Record was called that returned what the
+// real service returned.  Calls that returned mock, filled or zero values are skipped, since every field of them would
+// differ.  Calls recorded with redaction rules set are replayed with their redacted arguments
+func VerifyRecorded(realService interface{}, mockAlias string, opts ...RelayOption) []Drift {
+	var calls []Call
+	for _, call := range recorded() {
+		if call.alias == mockAlias && call.source == sourceReal {
+			calls = append(calls, call)
+		}
+	}
+	return verifyCalls(realService, mockAlias, calls, opts)
+}
+
+func verifyCalls(realService interface{}, mockAlias string, calls []Call, opts []RelayOption) []Drift {
+	if reason := realRelayBlocked(); reason != "" {
+		emitf("!!! vmockhelper: %s will not be verified because %s !!!", mockAlias, reason)
+		return nil
+	}
+	relay := newRelayConfig(opts)
+	if err := relay.checkAdapters(nil); err != nil {
+		emitf("vmockhelper: %s will not be verified: %s", mockAlias, err.Error())
+		return nil
+	}
+	var drifts []Drift
+	for _, call := range calls {
+		if !relay.relays(call.method) {
+			continue
+		}
+		method := relay.method(realService, call.method)
+		if !method.IsValid() {
+			emitf("vmockhelper: the real service for %s has no method %s", mockAlias, relay.realName(call.method))
+			continue
+		}
+		args := make([]reflect.Value, len(call.args))
+		for i, arg := range call.args {
+			if !arg.IsValid() && i < method.Type().NumIn() {
+				arg = reflect.Zero(method.Type().In(i))
+			}
+			if arg.IsValid() && isContext(arg) {
+				arg = reflect.ValueOf(context.Background())
+			}
+			args[i] = arg
+		}
+		if err := checkArgs(method.Type(), args); err != nil {
+			emitf("vmockhelper: %s.%s can not be verified: %s", mockAlias, call.method, err.Error())
+			continue
+		}
+		if !relay.budget.spend() {
+			maxCalls, _ := relay.budget.limit()
+			emitf("vmockhelper: %s.%s was not verified because it is over the budget of %d real calls", mockAlias, call.method, maxCalls)
+			continue
+		}
+		callDrifts, err := verifyCall(realService, relay, mockAlias, call, args)
+		if err != nil {
+			emitf("vmockhelper: %s.%s can not be verified: %s", mockAlias, call.method, err.Error())
+			continue
+		}
+		drifts = append(drifts, callDrifts...)
+	}
+	return drifts
+}
+
+// verifyCall calls the real service with args and compares what it returns with what call returned, which must already
+// be redacted.  A panic in the real service is returned as an error
+func verifyCall(realService interface{}, relay *relayConfig, mockAlias string, call Call, args []reflect.Value) (drifts []Drift, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("the real service panicked: %v", recovered)
+		}
+	}()
+	_, returns, note := relay.callReal(realService, call.method, args)
+	if note != "" {
+		emitf("vmockhelper: %s.%s: %s", mockAlias, call.method, note)
+	}
+	return compareReturns(mockAlias, call.method, call.returns, redact(returns)), nil
+}
+
+// checkArgs returns an error describing why args can not be passed to a function of type methodType, with any variadic
+// arguments passed as a slice the way a mock receives them, or nil if they can.  gomock matchers like gomock.Any() are
+// rejected, since the real service needs actual values
+func checkArgs(methodType reflect.Type, args []reflect.Value) error {
+	if len(args) != methodType.NumIn() {
+		return fmt.Errorf("it takes %d arguments, but %d were given", methodType.NumIn(), len(args))
+	}
+	for i, arg := range args {
+		if !arg.IsValid() {
+			return fmt.Errorf("argument %d is nil, which can not be a %s", i+1, methodType.In(i))
+		}
+		if _, ok := arg.Interface().(gomock.Matcher); ok {
+			return fmt.Errorf("argument %d is the matcher %q, but the real service needs an actual value", i+1, arg.Interface())
+		}
+		if !arg.Type().AssignableTo(methodType.In(i)) {
+			return fmt.Errorf("argument %d is a %s, which can not be a %s", i+1, arg.Type(), methodType.In(i))
+		}
+	}
+	return nil
+}
+
+// compareReturns prints and returns every difference between what a mock returned and what the real service returned.
+// The values are printed as they are, so both must already be redacted
+func compareReturns(mockAlias string, methodName string, mocked []reflect.Value, real []reflect.Value) []Drift {
+	var drifts []Drift
+	var diffs []string
+	for i := range real {
+		var expected reflect.Value
+		if i < len(mocked) {
+			expected = mocked[i]
+		}
+		for _, diff := range diffValues(fmt.Sprintf("Out%d", i+1), expected, real[i]) {
+			drift := Drift{Alias: mockAlias, Method: methodName, Path: diff[0], Expected: diff[1], Actual: diff[2]}
+			drifts = append(drifts, drift)
+			diffs = append(diffs, drift.String())
+		}
+	}
+	if len(diffs) > 0 {
+		emit("\n" + strings.Join(diffs, "\n") + "\n")
+	}
+	return drifts
+}
+
+// diffValues returns the path, expected code and actual code of every field where expected and actual differ
+func diffValues(path string, expected reflect.Value, actual reflect.Value) [][3]string {
+	difference := func() [][3]string {
+		return [][3]string{{path, renderValue(expected), renderValue(actual)}}
+	}
+	expected = indirectInterface(expected)
+	actual = indirectInterface(actual)
+	if !expected.IsValid() || !actual.IsValid() {
+		if expected.IsValid() != actual.IsValid() {
+			return difference()
+		}
+		return nil
+	}
+	if expected.Type() != actual.Type() {
+		return difference()
+	}
+	if _, ok := asError(expected); ok {
+		if renderValue(expected) != renderValue(actual) {
+			return difference()
+		}
+		return nil
+	}
+
+	switch expected.Kind() {
+	case reflect.Ptr:
+		if expected.IsNil() || actual.IsNil() {
+			if expected.IsNil() != actual.IsNil() {
+				return difference()
+			}
+			return nil
+		}
+		return diffValues(path, expected.Elem(), actual.Elem())
+	case reflect.Struct:
+		if expected.Type() == timeType {
+			if !expected.Interface().(time.Time).Equal(actual.Interface().(time.Time)) {
+				return difference()
+			}
+			return nil
+		}
+		var diffs [][3]string
+		for i := 0; i < expected.NumField(); i++ {
+			if !expected.Field(i).CanInterface() {
+				continue
+			}
+			diffs = append(diffs, diffValues(path+"."+expected.Type().Field(i).Name, expected.Field(i), actual.Field(i))...)
+		}
+		return diffs
+	case reflect.Slice, reflect.Array:
+		if expected.Len() != actual.Len() {
+			return difference()
+		}
+		var diffs [][3]string
+		for i := 0; i < expected.Len(); i++ {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), expected.Index(i), actual.Index(i))...)
+		}
+		return diffs
+	case reflect.Map:
+		var diffs [][3]string
+		for _, key := range expected.MapKeys() {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%s]", path, renderValue(key)), expected.MapIndex(key), actual.MapIndex(key))...)
+		}
+		for _, key := range actual.MapKeys() {
+			if !expected.MapIndex(key).IsValid() {
+				diffs = append(diffs, diffValues(fmt.Sprintf("%s[%s]", path, renderValue(key)), reflect.Value{}, actual.MapIndex(key))...)
+			}
+		}
+		return diffs
+	}
+	if renderValue(expected) != renderValue(actual) {
+		return difference()
+	}
+	return nil
+}
+
+// indirectInterface returns the value held by an interface, or v if it is not an interface
+func indirectInterface(v reflect.Value) reflect.Value {
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		return v.Elem()
+	}
+	return v
+}
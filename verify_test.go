@@ -0,0 +1,56 @@
+package vmockhelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyReportsDrift(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+
+	drifts := Verify(&fakeService{name: "Bob"}, "svc", []Expectation{
+		{Method: "Get", Args: []interface{}{context.Background(), "1"}, Returns: []interface{}{&testThing{ID: "1", Name: "Alice"}, nil}},
+		{Method: "Get", Args: []interface{}{context.Background(), "2"}, Returns: []interface{}{&testThing{ID: "2", Name: "Bob"}, nil}},
+	})
+
+	assert.Equal(t, []Drift{{Alias: "svc", Method: "Get", Path: "Out1.Name", Expected: `"Alice"`, Actual: `"Bob"`}}, drifts)
+	assert.Contains(t, buffer.String(), `svc.Get Out1.Name: expected "Alice", real service returned "Bob"`)
+}
+
+func TestVerifyRedactsDrift(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	buffer := useBuffer(t)
+	Clear()
+	Redact(RedactFields("Name"))
+	defer Redact()
+
+	drifts := Verify(&fakeService{name: "Bob Secret"}, "svc", []Expectation{
+		{Method: "Get", Args: []interface{}{context.Background(), "1"}, Returns: []interface{}{&testThing{ID: "1", Name: "Alice Secret"}, nil}},
+	})
+
+	assert.Equal(t, []Drift{{Alias: "svc", Method: "Get", Path: "Out1.Name", Expected: `"redacted-1"`, Actual: `"redacted-2"`}}, drifts)
+	assert.NotContains(t, buffer.String(), "Secret")
+}
+
+func TestVerifyRecordedComparesRedactedValues(t *testing.T) {
+	t.Setenv(allowRealEnvVar, "true")
+	useBuffer(t)
+	Clear()
+	defer Clear()
+	Redact(RedactFields("Name"))
+	defer Redact()
+
+	real := &fakeService{name: "Alice"}
+	mock := NewMockTestService(gomock.NewController(t))
+	UseRealAndPrintExpected(mock, real, "svc")
+	Record()
+	_, _ = mock.Get(context.Background(), "1")
+
+	assert.Empty(t, VerifyRecorded(real, "svc"))
+	drifts := VerifyRecorded(&fakeService{name: "Bob"}, "svc")
+	assert.Equal(t, []Drift{{Alias: "svc", Method: "Get", Path: "Out1.Name", Expected: `"redacted-1"`, Actual: `"redacted-2"`}}, drifts)
+}